@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/LibenHailu/cncg-bot/internal/config"
+	"github.com/LibenHailu/cncg-bot/internal/poster"
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+// command is a CLI subcommand; name may be several words ("sources check").
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context) error
+}
+
+var commands = []command{
+	{"daemon", "run fetch and post on the scheduler cron specs until SIGTERM", runDaemon},
+	{"fetch", "fetch, score and store new items (Pipeline.RunOnce only)", cmdFetch},
+	{"post", "send the next batch of unposted items", cmdPost},
+	{"digest", "send every email digest now", cmdDigest},
+	{"preview", "print the next batch as it would be sent, without sending", cmdPreview},
+	{"sources check", "probe every configured source and report failures", cmdSourcesCheck},
+	{"sources health", "list sources whose latest fetch failed", cmdSourcesHealth},
+	{"dead-letters", "list the deliveries destinations gave up on, with their payloads", cmdDeadLetters},
+	{"db migrate", "create or upgrade the database schema", cmdDBMigrate},
+}
+
+// lookup finds the command named by the leading args and returns the args
+// that follow its name.
+func lookup(args []string) (command, []string, bool) {
+	for _, c := range commands {
+		n := len(strings.Fields(c.name))
+		if len(args) >= n && strings.Join(args[:n], " ") == c.name {
+			return c, args[n:], true
+		}
+	}
+	return command{}, nil, false
+}
+
+// withStore opens the store, runs fn and releases the store, reporting the
+// first error.
+func withStore(ctx context.Context, cfg config.Config, fn func(db *store.Store) error) (err error) {
+	db, release, err := openStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rerr := release(ctx); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+	return fn(db)
+}
+
+func cmdFetch(ctx context.Context) error {
+	cfg, err := loadConfig(false)
+	if err != nil {
+		return err
+	}
+	return withStore(ctx, cfg, func(db *store.Store) error {
+		p := newPipeline(cfg, db)
+		if err := runFetch(ctx, p); err != nil {
+			return err
+		}
+		if !cfg.DryRun.Enabled {
+			return nil
+		}
+		out, closeOut, err := newDryRun(cfg)
+		if err != nil {
+			return err
+		}
+		defer closeOut()
+		for _, it := range p.Pending {
+			if _, err := out.Publish(ctx, it); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func cmdPost(ctx context.Context) error {
+	cfg, err := loadConfig(true)
+	if err != nil {
+		return err
+	}
+	pubs, closePubs, err := newPublishers(cfg)
+	if err != nil {
+		return err
+	}
+	defer closePubs()
+	return withStore(ctx, cfg, func(db *store.Store) error {
+		return runPost(ctx, cfg, newPipeline(cfg, db), pubs)
+	})
+}
+
+func cmdPreview(ctx context.Context) error {
+	cfg, err := loadConfig(false)
+	if err != nil {
+		return err
+	}
+	return withStore(ctx, cfg, func(db *store.Store) error {
+		items, err := newPipeline(cfg, db).NextBatch(ctx, channelNames(cfg), cfg.Scheduler.BatchSize)
+		if err != nil {
+			return err
+		}
+		if len(items) == 0 {
+			fmt.Println("nothing to post")
+			return nil
+		}
+		out := &poster.DryRun{W: os.Stdout, ParseMode: cfg.Telegram.ParseMode, Template: telegramTemplate(cfg)}
+		for _, it := range items {
+			if _, err := out.Publish(ctx, it); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func cmdSourcesCheck(ctx context.Context) error {
+	cfg, err := loadConfig(false)
+	if err != nil {
+		return err
+	}
+	results := newPipeline(cfg, nil).Probe(ctx)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SOURCE\tTYPE\tITEMS\tTIME\tSTATUS")
+	failed := 0
+	for _, r := range results {
+		status := "ok"
+		if r.Err != nil {
+			status = r.Err.Error()
+			failed++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%s\n", r.Source.Name, r.Source.Type, r.Items, r.Elapsed.Round(time.Millisecond), status)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d sources failed", failed, len(results))
+	}
+	return nil
+}
+
+func cmdSourcesHealth(ctx context.Context) error {
+	cfg, err := loadConfig(false)
+	if err != nil {
+		return err
+	}
+	return withStore(ctx, cfg, func(db *store.Store) error {
+		states, err := db.UnhealthySources(ctx)
+		if err != nil {
+			return err
+		}
+		if len(states) == 0 {
+			fmt.Println("all sources healthy")
+			return nil
+		}
+		now := time.Now()
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "SOURCE\tFAILURES\tLAST SUCCESS\tQUARANTINED UNTIL\tLAST ERROR")
+		for _, st := range states {
+			fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n", st.Name, st.ConsecutiveFailures,
+				fmtTime(st.LastSuccess), fmtQuarantine(st, now), st.LastError)
+		}
+		return tw.Flush()
+	})
+}
+
+func cmdDeadLetters(ctx context.Context) error {
+	cfg, err := loadConfig(false)
+	if err != nil {
+		return err
+	}
+	return withStore(ctx, cfg, func(db *store.Store) error {
+		var dls []store.DeadLetter
+		for _, d := range cfg.Publishers() {
+			if d.Type == "email" {
+				continue
+			}
+			got, err := db.DeadLetters(ctx, d.Name)
+			if err != nil {
+				return err
+			}
+			dls = append(dls, got...)
+		}
+		if len(dls) == 0 {
+			fmt.Println("no dead letters")
+			return nil
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "DESTINATION\tITEM\tATTEMPTS\tFAILED\tLAST ERROR\tPAYLOAD")
+		for _, d := range dls {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%s\t%s\t%s\n", d.Destination, d.ItemID, d.Attempts,
+				fmtTime(d.CreatedAt), d.LastError, d.Payload)
+		}
+		return tw.Flush()
+	})
+}
+
+func cmdDBMigrate(ctx context.Context) error {
+	cfg, err := loadConfig(false)
+	if err != nil {
+		return err
+	}
+	// Open runs the migrations; withStore also pushes an S3 snapshot back.
+	return withStore(ctx, cfg, func(db *store.Store) error {
+		fmt.Printf("%s schema is up to date\n", db.Dialect())
+		return nil
+	})
+}
+
+func fmtTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func fmtQuarantine(st store.SourceState, now time.Time) string {
+	if !st.Quarantined(now) {
+		return "-"
+	}
+	return fmtTime(st.NextAttempt)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/robfig/cron/v3"
+)
+
+// runDaemon keeps the process alive and runs the fetch and post jobs, and
+// any email digests, on their own cron specs until SIGTERM/SIGINT. The
+// signal only stops the scheduling: a running job finishes its batch before
+// the store is closed. A second signal kills the process.
+func runDaemon(ctx context.Context) error {
+	sig, stop := signal.NotifyContext(ctx, syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+
+	cfg, err := loadConfig(true)
+	if err != nil {
+		return err
+	}
+
+	db, release, err := openStore(ctx, cfg)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := release(ctx); err != nil {
+			log.Println("daemon: release store:", err)
+		}
+	}()
+
+	p := newPipeline(cfg, db)
+	pubs, closePubs, err := newPublishers(cfg)
+	if err != nil {
+		return err
+	}
+	defer closePubs()
+
+	logger := cron.PrintfLogger(log.New(os.Stderr, "cron: ", log.LstdFlags))
+	c := cron.New(
+		cron.WithLogger(logger),
+		cron.WithChain(cron.Recover(logger), cron.SkipIfStillRunning(logger)),
+	)
+	if _, err := c.AddFunc(cfg.Scheduler.FetchSpec(), func() {
+		if err := runFetch(ctx, p); err != nil {
+			log.Println("fetch:", err)
+		}
+	}); err != nil {
+		return err
+	}
+	if _, err := c.AddFunc(cfg.Scheduler.PostSpec(), func() {
+		if err := runPost(ctx, cfg, p, pubs); err != nil {
+			log.Println("post:", err)
+		}
+	}); err != nil {
+		return err
+	}
+
+	for _, d := range digests(cfg) {
+		if _, err := c.AddFunc(d.Email.CronSpec, func() {
+			if err := runDigest(ctx, cfg, db, d, dryRunOf(pubs)); err != nil {
+				log.Printf("digest %s: %v", d.Name, err)
+			}
+		}); err != nil {
+			return err
+		}
+		log.Printf("daemon: digest %s %q", d.Name, d.Email.CronSpec)
+	}
+
+	log.Printf("daemon: fetch %q, post %q", cfg.Scheduler.FetchSpec(), cfg.Scheduler.PostSpec())
+	c.Start()
+	<-sig.Done()
+	stop()
+
+	log.Println("daemon: shutting down")
+	<-c.Stop().Done()
+	return nil
+}
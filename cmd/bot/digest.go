@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/LibenHailu/cncg-bot/internal/config"
+	"github.com/LibenHailu/cncg-bot/internal/poster"
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+// digests returns the configured email digest destinations.
+func digests(cfg config.Config) []config.Destination {
+	var out []config.Destination
+	for _, d := range cfg.Publishers() {
+		if d.Type == "email" {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+func newDigest(d config.Destination) *poster.Digest {
+	e := d.Email
+	var auth smtp.Auth
+	if e.Username != "" {
+		host, _, _ := net.SplitHostPort(e.SMTPAddr)
+		auth = smtp.PlainAuth("", e.Username, e.Password, host)
+	}
+	return &poster.Digest{
+		Addr: e.SMTPAddr, Auth: auth, From: e.From, To: e.To,
+		Subject: e.Subject, GroupBy: e.GroupBy, Dest: d.Name,
+	}
+}
+
+// runDigest mails destination d the items of its window it has not had
+// yet and records them as delivered. In dry-run mode the plaintext part is
+// written to dry, the run's dry-run output, instead and nothing is recorded.
+func runDigest(ctx context.Context, cfg config.Config, db *store.Store, d config.Destination, dry *poster.DryRun) error {
+	dg := newDigest(d)
+	since := time.Now().Add(-d.Email.Window)
+	items, err := db.NextUndelivered(ctx, dg.Name(), cfg.Filters.MinScore, since, d.Email.Limit)
+	if err != nil {
+		db.LogError(ctx, "digest:select", err.Error())
+		return err
+	}
+	if len(items) == 0 {
+		return nil
+	}
+
+	if cfg.DryRun.Enabled {
+		text, err := dg.RenderText(items, time.Now())
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(dry.W, "--- digest %s to %v\n\n%s\n", dg.Name(), d.Email.To, text)
+		return err
+	}
+
+	if err := dg.Send(ctx, items); err != nil {
+		db.LogError(ctx, "publish:"+dg.Name(), err.Error())
+		return err
+	}
+	for _, it := range items {
+		if err := db.RecordPost(ctx, store.Post{ItemID: it.ID, Destination: dg.Name(), Status: store.PostSent}); err != nil {
+			log.Println("record post error:", err)
+		}
+	}
+	return nil
+}
+
+// sendDueDigests sends the digests whose cron spec has fired since they
+// last went out. The Lambda handler has no cron of its own, so a digest
+// goes out on the first invocation at or after its scheduled time.
+func sendDueDigests(ctx context.Context, cfg config.Config, db *store.Store, dry *poster.DryRun) {
+	now := time.Now()
+	for _, d := range digests(cfg) {
+		sched, err := cron.ParseStandard(d.Email.CronSpec)
+		if err != nil {
+			log.Printf("digest %s: %v", d.Name, err)
+			continue
+		}
+		last, err := db.LastSent(ctx, d.Name)
+		if err != nil {
+			db.LogError(ctx, "digest:select", err.Error())
+			continue
+		}
+		if sched.Next(last).After(now) {
+			continue
+		}
+		if err := runDigest(ctx, cfg, db, d, dry); err != nil {
+			log.Printf("digest %s: %v", d.Name, err)
+		}
+	}
+}
+
+func cmdDigest(ctx context.Context) error {
+	cfg, err := loadConfig(false)
+	if err != nil {
+		return err
+	}
+	ds := digests(cfg)
+	if len(ds) == 0 {
+		return fmt.Errorf("no email destinations configured")
+	}
+	var dry *poster.DryRun
+	if cfg.DryRun.Enabled {
+		var closeOut func() error
+		if dry, closeOut, err = newDryRun(cfg); err != nil {
+			return err
+		}
+		defer closeOut()
+	}
+	return withStore(ctx, cfg, func(db *store.Store) error {
+		for _, d := range ds {
+			if err := runDigest(ctx, cfg, db, d, dry); err != nil {
+				return fmt.Errorf("%s: %w", d.Name, err)
+			}
+		}
+		return nil
+	})
+}
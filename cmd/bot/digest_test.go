@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/LibenHailu/cncg-bot/internal/config"
+)
+
+// TestDryRunDigest runs the post job and then a due digest in dry-run mode,
+// as the Lambda handler does: both write to the one dry_run.output.
+func TestDryRunDigest(t *testing.T) {
+	var cfg config.Config
+	cfg.Scheduler.BatchSize = 2
+	cfg.DryRun.Enabled = true
+	cfg.DryRun.Output = filepath.Join(t.TempDir(), "dry-run.txt")
+	cfg.Destinations = []config.Destination{
+		{Type: "telegram"},
+		{Name: "weekly", Type: "email", Email: config.EmailDigest{From: "bot@example.com", To: []string{"team@example.com"}}},
+	}
+
+	ctx := context.Background()
+	p := newTestPipeline(t, 2)
+	pubs, closePubs, err := newPublishers(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := runPost(ctx, cfg, p, pubs); err != nil {
+		t.Fatal(err)
+	}
+	sendDueDigests(ctx, cfg, p.DB, dryRunOf(pubs))
+	if err := closePubs(); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := os.ReadFile(cfg.DryRun.Output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"--- #1 ", "--- #2 ", "--- digest weekly to [team@example.com]"} {
+		if !strings.Contains(string(out), want) {
+			t.Errorf("dry-run output lacks %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/LibenHailu/cncg-bot/internal/config"
+	"github.com/LibenHailu/cncg-bot/internal/core"
+	"github.com/LibenHailu/cncg-bot/internal/fetch"
+	"github.com/LibenHailu/cncg-bot/internal/poster"
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+// loadConfig loads and validates the config named by -config/CONFIG_PATH.
+// Commands that never post pass needTelegram=false so they run without
+// bot credentials.
+func loadConfig(needTelegram bool) (config.Config, error) {
+	cfg, err := config.Load(config.Path(*configPath))
+	if err != nil {
+		return cfg, err
+	}
+	err = cfg.Validate()
+	if errs := checkTemplates(cfg); len(errs) > 0 {
+		var ve *config.ValidationError
+		if !errors.As(err, &ve) {
+			ve = &config.ValidationError{}
+		}
+		ve.Errors = append(ve.Errors, errs...)
+		err = ve
+	}
+	if *dryRun {
+		cfg.DryRun.Enabled = true
+	}
+	var ve *config.ValidationError
+	if (!needTelegram || cfg.DryRun.Enabled) && errors.As(err, &ve) {
+		err = ve.Without("telegram.")
+	}
+	return cfg, err
+}
+
+func newPipeline(cfg config.Config, db *store.Store) *core.Pipeline {
+	p := &core.Pipeline{
+		Filters: core.Filters{
+			MaxAgeDays: cfg.Filters.MaxAgeDays,
+			MinScore:   cfg.Filters.MinScore,
+			Positive:   keywords(cfg.Keywords.Positive),
+			Negative:   keywords(cfg.Keywords.Negative),
+		},
+		DB:            db,
+		Concurrency:   cfg.Fetch.Concurrency,
+		SourceTimeout: cfg.Fetch.Timeout,
+		OGImage:       cfg.Fetch.OGImage,
+		Scoring: &core.Scoring{
+			SourceWeight:   cfg.Scoring.SourceWeight,
+			KeywordWeight:  cfg.Scoring.KeywordWeight,
+			NegativeWeight: cfg.Scoring.NegativeWeight,
+			TitleBoost:     cfg.Scoring.TitleBoost,
+			HalfLife:       cfg.Scoring.HalfLife,
+		},
+		Health: core.HealthPolicy{
+			QuarantineAfter: cfg.Health.QuarantineAfter,
+			Backoff:         cfg.Health.Backoff,
+			MaxBackoff:      cfg.Health.MaxBackoff,
+		},
+		DryRun: cfg.DryRun.Enabled,
+	}
+	if cfg.Dedup.Enabled {
+		p.Dedup = &core.Dedup{MaxDistance: cfg.Dedup.MaxDistance, Window: cfg.Dedup.Window}
+	}
+	for _, s := range cfg.Sources {
+		p.Sources = append(p.Sources, core.SourceCfg{
+			Name: s.Name, Type: s.Type, URL: s.URL, Weight: s.Weight, Tags: s.Tags,
+			Selectors: fetch.Selectors{
+				Item:    s.Selectors.Item,
+				Title:   s.Selectors.Title,
+				Link:    s.Selectors.Link,
+				Date:    s.Selectors.Date,
+				Summary: s.Selectors.Summary,
+				Image:   s.Selectors.Image,
+			},
+		})
+	}
+	return p
+}
+
+func keywords(in []config.Keyword) []core.Keyword {
+	out := make([]core.Keyword, len(in))
+	for i, kw := range in {
+		out[i] = core.Keyword{Term: kw.Term, Weight: kw.Weight}
+	}
+	return out
+}
+
+// runFetch runs the pipeline once: fetch, score and store new items.
+func runFetch(ctx context.Context, p *core.Pipeline) error {
+	if err := p.RunOnce(ctx); err != nil {
+		p.DB.LogError(ctx, "pipeline", err.Error())
+		return err
+	}
+	return nil
+}
+
+// newPublishers builds a Publisher for every configured destination, or in
+// dry-run mode a single writer to dry_run.output. close must be called when
+// done.
+func newPublishers(cfg config.Config) (pubs []poster.Publisher, close func() error, err error) {
+	if cfg.DryRun.Enabled {
+		out, close, err := newDryRun(cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		return []poster.Publisher{out}, close, nil
+	}
+	for _, d := range cfg.Publishers() {
+		tmpl, err := destTemplate(cfg, d)
+		if err != nil {
+			return nil, nil, fmt.Errorf("destination %s: %w", d.Name, err)
+		}
+		switch d.Type {
+		case "telegram":
+			tg, err := poster.New(cfg.Telegram.BotToken, cfg.Telegram.ChannelID, cfg.Telegram.ParseMode)
+			if err != nil {
+				return nil, nil, fmt.Errorf("destination %s: %w", d.Name, err)
+			}
+			tg.Dest = d.Name
+			tg.Template = tmpl
+			tg.Interval = cfg.Telegram.SendInterval
+			tg.MaxAttempts = cfg.Telegram.MaxAttempts
+			pubs = append(pubs, tg)
+		case "slack":
+			pubs = append(pubs, &poster.Slack{WebhookURL: d.WebhookURL, Dest: d.Name})
+		case "discord":
+			pubs = append(pubs, &poster.Discord{WebhookURL: d.WebhookURL, Dest: d.Name})
+		case "mastodon":
+			pubs = append(pubs, &poster.Mastodon{Server: d.Server, AccessToken: d.AccessToken, Dest: d.Name, Template: tmpl})
+		case "webhook":
+			pubs = append(pubs, &poster.Webhook{
+				URL: d.WebhookURL, Secret: d.Secret, MaxAttempts: d.MaxAttempts, Backoff: d.Backoff, Dest: d.Name,
+			})
+		case "email":
+			// Digests are sent by their own job, see runDigest.
+		default:
+			return nil, nil, fmt.Errorf("destination %s: unsupported type %q", d.Name, d.Type)
+		}
+	}
+	return pubs, func() error { return nil }, nil
+}
+
+// dryRunOf returns the DryRun among pubs, which newPublishers makes in
+// dry-run mode, so other jobs of the run can write to the same output.
+func dryRunOf(pubs []poster.Publisher) *poster.DryRun {
+	for _, pub := range pubs {
+		if dry, ok := pub.(*poster.DryRun); ok {
+			return dry
+		}
+	}
+	return nil
+}
+
+// newDryRun returns a DryRun writing to dry_run.output (stdout for "" or "-").
+// Each call truncates the file, so a run makes only one.
+func newDryRun(cfg config.Config) (*poster.DryRun, func() error, error) {
+	if cfg.DryRun.Output == "" || cfg.DryRun.Output == "-" {
+		return &poster.DryRun{W: os.Stdout, ParseMode: cfg.Telegram.ParseMode, Template: telegramTemplate(cfg)}, func() error { return nil }, nil
+	}
+	f, err := os.Create(cfg.DryRun.Output)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &poster.DryRun{W: f, ParseMode: cfg.Telegram.ParseMode, Template: telegramTemplate(cfg)}, f.Close, nil
+}
+
+// channelNames returns the names of the destinations runPost sends to:
+// every configured one but the email digests.
+func channelNames(cfg config.Config) []string {
+	var names []string
+	for _, d := range cfg.Publishers() {
+		if d.Type != "email" {
+			names = append(names, d.Name)
+		}
+	}
+	return names
+}
+
+// runPost sends the next batch of unposted items to every publisher and
+// records each attempt in the posts table. A destination that failed is
+// retried on the next run without re-sending to the others; one that gave
+// up (a dead letter or a permanent error) is not. A destination that is
+// unavailable as a whole gets no more sends this run: the rest of its items
+// are recorded as failed for the next one, and the run stops once every
+// destination is down. In dry-run mode nothing is recorded.
+func runPost(ctx context.Context, cfg config.Config, p *core.Pipeline, pubs []poster.Publisher) error {
+	db := p.DB
+	names := make([]string, len(pubs))
+	for i, pub := range pubs {
+		names[i] = pub.Name()
+	}
+	if cfg.DryRun.Enabled {
+		// The dry-run writer stands in for the configured destinations:
+		// select what they would be sent.
+		names = channelNames(cfg)
+	}
+	items, err := p.NextBatch(ctx, names, cfg.Scheduler.BatchSize)
+	if err != nil {
+		db.LogError(ctx, "schedule:select", err.Error())
+		return err
+	}
+
+	down := map[string]error{}
+	for _, it := range items {
+		if cfg.DryRun.Enabled {
+			for _, pub := range pubs {
+				if _, err := pub.Publish(ctx, it); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		posts, err := db.Posts(ctx, it.ID)
+		if err != nil {
+			db.LogError(ctx, "schedule:posts", err.Error())
+			continue
+		}
+		for _, pub := range pubs {
+			prev := posts[pub.Name()]
+			if prev.Status == store.PostSent || prev.Status == store.PostDead {
+				continue
+			}
+			post := store.Post{
+				ItemID: it.ID, Destination: pub.Name(), Status: store.PostFailed,
+				RemoteID: prev.RemoteID, Replies: prev.Replies, // keep a partial chain on record
+			}
+			if err := down[pub.Name()]; err != nil {
+				post.Error = err.Error()
+			} else if post, err = publish(ctx, db, pub, it, prev); errors.Is(err, poster.ErrDestination) {
+				down[pub.Name()] = err
+			}
+			// Record a send that went out even if ctx was cancelled
+			// meanwhile, or it goes out again next run.
+			if err := db.RecordPost(context.WithoutCancel(ctx), post); err != nil {
+				log.Println("record post error:", err)
+			}
+		}
+		if len(down) > 0 && len(down) == len(pubs) {
+			var errs []error
+			for _, err := range down {
+				errs = append(errs, err)
+			}
+			return fmt.Errorf("every destination is unavailable: %w", errors.Join(errs...))
+		}
+	}
+	return nil
+}
+
+// publish delivers it through pub and describes the outcome as a post,
+// returning the delivery error alongside. When prev, the failed attempt
+// before, got part of a reply chain out, a Resumer sends only the rest.
+func publish(ctx context.Context, db *store.Store, pub poster.Publisher, it store.Item, prev store.Post) (store.Post, error) {
+	post := store.Post{ItemID: it.ID, Destination: pub.Name(), Status: store.PostSent}
+	var rc poster.Receipt
+	var err error
+	if r, ok := pub.(poster.Resumer); ok && prev.RemoteID != "" {
+		rc, err = r.Resume(ctx, it, poster.Receipt{RemoteID: prev.RemoteID, Replies: prev.Replies})
+	} else {
+		rc, err = pub.Publish(ctx, it)
+	}
+	// A reply chain broken halfway still names the messages that went out.
+	post.RemoteID, post.Replies = rc.RemoteID, rc.Replies
+	if err == nil {
+		post.PostedAt = rc.At
+		return post, nil
+	}
+
+	db.LogError(ctx, "publish:"+pub.Name(), err.Error())
+	post.Status, post.Error = store.PostFailed, err.Error()
+	var ue *poster.UndeliverableError
+	switch {
+	case errors.Is(err, poster.ErrDestination):
+		// Not the item's fault: keep it for when the destination is back.
+	case errors.As(err, &ue):
+		if dlErr := db.AddDeadLetter(context.WithoutCancel(ctx), store.DeadLetter{
+			Destination: pub.Name(), ItemID: it.ID, Payload: string(ue.Payload),
+			Attempts: ue.Attempts, LastError: ue.Err.Error(),
+		}); dlErr != nil {
+			// Without the payload on record, keep retrying instead.
+			log.Println("dead letter error:", dlErr)
+			return post, err
+		}
+		post.Status = store.PostDead
+	case errors.Is(err, poster.ErrPermanent):
+		post.Status = store.PostDead
+	}
+	return post, err
+}
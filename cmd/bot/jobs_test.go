@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LibenHailu/cncg-bot/internal/config"
+	"github.com/LibenHailu/cncg-bot/internal/core"
+	"github.com/LibenHailu/cncg-bot/internal/poster"
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+// fakePub answers every Publish with err and counts the calls.
+type fakePub struct {
+	name  string
+	err   error
+	calls int
+}
+
+func (f *fakePub) Name() string { return f.name }
+
+func (f *fakePub) Publish(context.Context, store.Item) (poster.Receipt, error) {
+	f.calls++
+	return poster.Receipt{RemoteID: "1"}, f.err
+}
+
+func newTestPipeline(t *testing.T, n int) *core.Pipeline {
+	t.Helper()
+	db, err := store.Open("file:" + filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	for i := 0; i < n; i++ {
+		title := fmt.Sprint("item ", i)
+		if _, err := db.InsertIfNew(context.Background(), store.Item{
+			Source: "test", Title: title, URL: "https://example.com/" + title,
+			PublishedAt: time.Now().UTC(), Hash: store.Hash(title, title), Score: 1,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return &core.Pipeline{DB: db, Filters: core.Filters{MaxAgeDays: 1}}
+}
+
+// statuses returns the post status of every item at dest, in item order.
+func statuses(t *testing.T, db *store.Store, dest string, n int) []string {
+	t.Helper()
+	var out []string
+	for id := int64(1); id <= int64(n); id++ {
+		posts, err := db.Posts(context.Background(), id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, posts[dest].Status)
+	}
+	return out
+}
+
+func TestRunPostDestinationDown(t *testing.T) {
+	var cfg config.Config
+	cfg.Scheduler.BatchSize = 3
+	kicked := fmt.Errorf("%w: Forbidden: bot was kicked", poster.ErrDestination)
+
+	t.Run("one of two", func(t *testing.T) {
+		p := newTestPipeline(t, 3)
+		tg := &fakePub{name: "telegram", err: kicked}
+		slack := &fakePub{name: "slack"}
+		if err := runPost(context.Background(), cfg, p, []poster.Publisher{tg, slack}); err != nil {
+			t.Fatalf("runPost = %v", err)
+		}
+		if tg.calls != 1 || slack.calls != 3 {
+			t.Errorf("calls telegram=%d slack=%d, want 1 and 3", tg.calls, slack.calls)
+		}
+		for _, st := range statuses(t, p.DB, "telegram", 3) {
+			if st != store.PostFailed {
+				t.Errorf("telegram statuses = %v, want all failed", statuses(t, p.DB, "telegram", 3))
+				break
+			}
+		}
+	})
+
+	t.Run("all", func(t *testing.T) {
+		p := newTestPipeline(t, 3)
+		tg := &fakePub{name: "telegram", err: kicked}
+		err := runPost(context.Background(), cfg, p, []poster.Publisher{tg})
+		if !errors.Is(err, poster.ErrDestination) {
+			t.Fatalf("runPost = %v, want the run stopped with ErrDestination", err)
+		}
+		if tg.calls != 1 {
+			t.Errorf("%d sends, want 1", tg.calls)
+		}
+		// The item tried is kept for retry, the others were not touched.
+		got := statuses(t, p.DB, "telegram", 3)
+		sort.Strings(got)
+		if want := fmt.Sprint([]string{"", "", store.PostFailed}); fmt.Sprint(got) != want {
+			t.Errorf("statuses = %v, want %s", got, want)
+		}
+	})
+
+	t.Run("bad item", func(t *testing.T) {
+		p := newTestPipeline(t, 2)
+		tg := &fakePub{name: "telegram", err: fmt.Errorf("%w: can't parse entities", poster.ErrPermanent)}
+		if err := runPost(context.Background(), cfg, p, []poster.Publisher{tg}); err != nil {
+			t.Fatalf("runPost = %v", err)
+		}
+		if tg.calls != 2 {
+			t.Errorf("%d sends, want one per item", tg.calls)
+		}
+		got := fmt.Sprint(statuses(t, p.DB, "telegram", 2))
+		if want := fmt.Sprint([]string{store.PostDead, store.PostDead}); got != want {
+			t.Errorf("statuses = %s, want %s", got, want)
+		}
+	})
+}
+
+// chainPub sends every item as a chain of three messages; the first
+// attempt stops after two.
+type chainPub struct {
+	fakePub
+	resumed []poster.Receipt
+}
+
+func (c *chainPub) Publish(context.Context, store.Item) (poster.Receipt, error) {
+	c.calls++
+	return poster.Receipt{RemoteID: "1", Replies: []string{"2"}}, errors.New("part 3 of 3: timeout")
+}
+
+func (c *chainPub) Resume(_ context.Context, _ store.Item, partial poster.Receipt) (poster.Receipt, error) {
+	c.resumed = append(c.resumed, partial)
+	partial.Replies = append(partial.Replies, "3")
+	return partial, nil
+}
+
+func TestRunPostResumesChain(t *testing.T) {
+	var cfg config.Config
+	cfg.Scheduler.BatchSize = 1
+	p := newTestPipeline(t, 1)
+	pub := &chainPub{fakePub: fakePub{name: "telegram"}}
+	for run := 0; run < 2; run++ {
+		if err := runPost(context.Background(), cfg, p, []poster.Publisher{pub}); err != nil {
+			t.Fatalf("run %d: %v", run, err)
+		}
+	}
+	if pub.calls != 1 || len(pub.resumed) != 1 {
+		t.Fatalf("Publish %d times, Resume %d times; want one each", pub.calls, len(pub.resumed))
+	}
+	if got := pub.resumed[0]; got.RemoteID != "1" || fmt.Sprint(got.Replies) != "[2]" {
+		t.Errorf("resumed from %+v, want the two messages of the first attempt", got)
+	}
+	posts, err := p.DB.Posts(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if post := posts["telegram"]; post.Status != store.PostSent || fmt.Sprint(post.Replies) != "[2 3]" {
+		t.Errorf("post = %+v, want sent with the whole chain", post)
+	}
+}
+
+// cancelPub cancels the run while its message goes out, like a SIGTERM
+// arriving mid-send.
+type cancelPub struct {
+	fakePub
+	cancel context.CancelFunc
+}
+
+func (c *cancelPub) Publish(ctx context.Context, it store.Item) (poster.Receipt, error) {
+	c.cancel()
+	return c.fakePub.Publish(ctx, it)
+}
+
+func TestRunPostRecordsAfterCancel(t *testing.T) {
+	var cfg config.Config
+	cfg.Scheduler.BatchSize = 1
+	p := newTestPipeline(t, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pub := &cancelPub{fakePub: fakePub{name: "telegram"}, cancel: cancel}
+	if err := runPost(ctx, cfg, p, []poster.Publisher{pub}); err != nil {
+		t.Fatal(err)
+	}
+	if got := statuses(t, p.DB, "telegram", 1); got[0] != store.PostSent {
+		t.Errorf("status = %q, want the delivery recorded as sent", got[0])
+	}
+}
+
+// TestRunPostDryRun checks that a dry run previews what the configured
+// destinations would be sent, not everything.
+func TestRunPostDryRun(t *testing.T) {
+	var cfg config.Config
+	cfg.Scheduler.BatchSize = 5
+	cfg.DryRun.Enabled = true
+	p := newTestPipeline(t, 2)
+	if err := p.DB.RecordPost(context.Background(), store.Post{ItemID: 1, Destination: "telegram", Status: store.PostSent}); err != nil {
+		t.Fatal(err)
+	}
+
+	var out strings.Builder
+	if err := runPost(context.Background(), cfg, p, []poster.Publisher{&poster.DryRun{W: &out}}); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "id=1 ") || !strings.Contains(out.String(), "id=2 ") {
+		t.Errorf("dry run printed:\n%s\nwant only the item telegram has not had", out.String())
+	}
+	if got := statuses(t, p.DB, "telegram", 2); got[1] != "" {
+		t.Errorf("dry run recorded %q", got[1])
+	}
+}
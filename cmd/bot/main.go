@@ -2,72 +2,83 @@ package main
 
 import (
 	"context"
-	"log"
+	"flag"
+	"fmt"
+	"os"
 
 	"github.com/aws/aws-lambda-go/lambda"
-
-	"github.com/LibenHailu/cncg-bot/internal/config"
-	"github.com/LibenHailu/cncg-bot/internal/core"
-	"github.com/LibenHailu/cncg-bot/internal/poster"
-	"github.com/LibenHailu/cncg-bot/internal/store"
 )
 
-func handler(ctx context.Context) error {
+var (
+	configPath = flag.String("config", "", "path to config.yaml (defaults to $CONFIG_PATH or ./config.yaml)")
+	dryRun     = flag.Bool("dry-run", false, "fetch, score and select without writing to the database or posting; print the messages instead")
+)
 
-	cfg := config.Load()
+func handler(ctx context.Context) (err error) {
 
-	db, err := store.Open(cfg.DBPath)
-	if err != nil {
-		log.Fatal("db:", err)
-	}
-
-	p := core.Pipeline{
-		Filters: core.Filters{
-			MaxAgeDays: cfg.Filters.MaxAgeDays,
-			MinScore:   cfg.Filters.MinScore,
-			Positive:   cfg.Keywords.Positive,
-			Negative:   cfg.Keywords.Negative,
-		},
-		DB: db,
-	}
-	for _, s := range cfg.Sources {
-		p.Sources = append(p.Sources, core.SourceCfg{
-			Name: s.Name, Type: s.Type, URL: s.URL, Weight: s.Weight, Tags: s.Tags,
-		})
-	}
-
-	tg, err := poster.New(cfg.Telegram.BotToken, cfg.Telegram.ChannelID, cfg.Telegram.ParseMode)
+	cfg, err := loadConfig(true)
 	if err != nil {
 		return err
 	}
 
-	// Run pipeline once
-	err = p.RunOnce(ctx)
+	db, release, err := openStore(ctx, cfg)
 	if err != nil {
-		db.LogError(ctx, "pipeline", err.Error())
 		return err
 	}
+	// Persist dedup/posted state even when the run fails part way.
+	defer func() {
+		if rerr := release(ctx); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+
+	p := newPipeline(cfg, db)
 
-	// Send next batch
-	items, err := db.NextUnposted(ctx, cfg.Filters.MinScore, cfg.Scheduler.BatchSize)
+	pubs, closePubs, err := newPublishers(cfg)
 	if err != nil {
-		db.LogError(ctx, "schedule:select", err.Error())
 		return err
 	}
+	defer closePubs()
 
-	for _, it := range items {
-		if err := tg.PostItem(ctx, it); err != nil {
-			db.LogError(ctx, "telegram:send", err.Error())
-			continue
-		}
-		if err := db.MarkPosted(ctx, it.ID); err != nil {
-			log.Println("mark posted error:", err)
-		}
+	// Run pipeline once
+	if err := runFetch(ctx, p); err != nil {
+		return err
 	}
 
-	return nil
+	// Send next batch to every destination, then any digest that is due
+	err = runPost(ctx, cfg, p, pubs)
+	sendDueDigests(ctx, cfg, db, dryRunOf(pubs))
+	return err
 }
 
 func main() {
-	lambda.Start(handler)
+	flag.Usage = func() {
+		out := flag.CommandLine.Output()
+		fmt.Fprintf(out, "usage: %s [flags] [command] [flags]\n\n", os.Args[0])
+		fmt.Fprintln(out, "With no command the binary runs as an AWS Lambda handler.\n\nCommands:")
+		for _, c := range commands {
+			fmt.Fprintf(out, "  %-16s %s\n", c.name, c.usage)
+		}
+		fmt.Fprintln(out, "\nFlags:")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		lambda.Start(handler)
+		return
+	}
+	cmd, rest, ok := lookup(flag.Args())
+	if ok {
+		// Flags may also follow the command: "bot fetch -dry-run".
+		_ = flag.CommandLine.Parse(rest) // exits on error
+	}
+	if !ok || flag.NArg() > 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(context.Background()); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cmd.name, err)
+		os.Exit(1)
+	}
 }
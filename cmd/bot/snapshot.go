@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+
+	"github.com/LibenHailu/cncg-bot/internal/config"
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+// newS3Client builds the client used for SQLite snapshots. Replaced in
+// tests or local runs to point at a fake.
+var newS3Client = func(ctx context.Context) (store.S3API, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s3.NewFromConfig(awsCfg), nil
+}
+
+// openStore opens the configured store. For a SQLite DSN with s3.bucket set
+// the database file is pulled from S3 first; the returned release func
+// closes the store and pushes the file back (except on a dry run).
+func openStore(ctx context.Context, cfg config.Config) (*store.Store, func(context.Context) error, error) {
+	var snap *store.S3Snapshot
+	if path, ok := store.SQLitePath(cfg.DBPath); ok && cfg.S3.Bucket != "" {
+		client, err := newS3Client(ctx)
+		if err != nil {
+			return nil, nil, err
+		}
+		snap = &store.S3Snapshot{Client: client, Bucket: cfg.S3.Bucket, Key: cfg.S3.Key, Path: path}
+		if err := snap.Download(ctx); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	db, err := store.Open(cfg.DBPath)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	release := func(ctx context.Context) error {
+		if err := db.Close(); err != nil {
+			log.Println("db close:", err)
+		}
+		if snap == nil || cfg.DryRun.Enabled {
+			return nil
+		}
+		return snap.Upload(ctx)
+	}
+	return db, release, nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/LibenHailu/cncg-bot/internal/config"
+	"github.com/LibenHailu/cncg-bot/internal/poster"
+)
+
+// destTemplate parses d's template, or returns nil when it has none.
+// Telegram templates format in telegram.parse_mode, Mastodon ones in plain
+// text.
+func destTemplate(cfg config.Config, d config.Destination) (*poster.Template, error) {
+	if d.Template == "" {
+		return nil, nil
+	}
+	switch d.Type {
+	case "telegram":
+		m, err := poster.MarkupFor(cfg.Telegram.ParseMode)
+		if err != nil {
+			return nil, err
+		}
+		return poster.ParseTemplate(d.Template, m)
+	case "mastodon":
+		return poster.ParseTemplate(d.Template, poster.Plain)
+	}
+	return nil, fmt.Errorf("%s destinations do not take a template", d.Type)
+}
+
+// checkTemplates parses every destination template and renders it with
+// poster.SampleItem, so a broken template fails at startup rather than on
+// the first post.
+func checkTemplates(cfg config.Config) []config.FieldError {
+	var errs []config.FieldError
+	for i, d := range cfg.Publishers() {
+		path := fmt.Sprintf("destinations[%d].template", i)
+		t, err := destTemplate(cfg, d)
+		if err == nil && t != nil {
+			_, err = t.Execute(poster.SampleItem)
+		}
+		if err != nil {
+			errs = append(errs, config.FieldError{Path: path, Msg: err.Error()})
+		}
+	}
+	return errs
+}
+
+// telegramTemplate is the template of the first telegram destination, used
+// to render dry runs; nil means the default layout.
+func telegramTemplate(cfg config.Config) *poster.Template {
+	for _, d := range cfg.Publishers() {
+		if d.Type == "telegram" {
+			t, _ := destTemplate(cfg, d)
+			return t
+		}
+	}
+	return nil
+}
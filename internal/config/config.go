@@ -1,46 +1,279 @@
 package config
 
 import (
+	"fmt"
 	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+	"gopkg.in/yaml.v3"
 )
 
+// DefaultPath is used when neither the -config flag nor CONFIG_PATH is set.
+const DefaultPath = "config.yaml"
+
 type Config struct {
 	Telegram struct {
 		BotToken  string `mapstructure:"bot_token"`
 		ChannelID string `mapstructure:"channel_id"`
 		ParseMode string `mapstructure:"parse_mode"`
-	}
-	Scheduler struct {
-		CronSpec  string `mapstructure:"cron_spec"`
-		BatchSize int    `mapstructure:"batch_size"`
-	}
-	Filters struct {
+		// SendInterval is the minimum gap between messages to the channel;
+		// MaxAttempts bounds retries of flood-control and transient errors.
+		SendInterval time.Duration `mapstructure:"send_interval"`
+		MaxAttempts  int           `mapstructure:"max_attempts"`
+	} `mapstructure:"telegram"`
+	// Destinations lists where items are published; empty means the
+	// telegram channel above only.
+	Destinations []Destination `mapstructure:"destinations"`
+	Scheduler    Scheduler     `mapstructure:"scheduler"`
+	Filters      struct {
 		MaxAgeDays int     `mapstructure:"max_age_days"`
 		MinScore   float64 `mapstructure:"min_score"`
-	}
+	} `mapstructure:"filters"`
 	Keywords struct {
-		Positive []string
-		Negative []string
+		Positive []Keyword `mapstructure:"positive"`
+		Negative []Keyword `mapstructure:"negative"`
+	} `mapstructure:"keywords"`
+	Scoring struct {
+		SourceWeight   float64       `mapstructure:"source_weight"`
+		KeywordWeight  float64       `mapstructure:"keyword_weight"`
+		NegativeWeight float64       `mapstructure:"negative_weight"`
+		TitleBoost     float64       `mapstructure:"title_boost"`
+		HalfLife       time.Duration `mapstructure:"half_life"`
+	} `mapstructure:"scoring"`
+	Fetch struct {
+		Concurrency int           `mapstructure:"concurrency"`
+		Timeout     time.Duration `mapstructure:"timeout"` // per source, e.g. "30s"
+		// OGImage looks up the og:image of new items that are good enough
+		// to post but came without an image.
+		OGImage bool `mapstructure:"og_image"`
+	} `mapstructure:"fetch"`
+	// Dedup links near-duplicate items (same story from several sources).
+	Dedup struct {
+		Enabled     bool          `mapstructure:"enabled"`
+		MaxDistance int           `mapstructure:"max_distance"`
+		Window      time.Duration `mapstructure:"window"`
+	} `mapstructure:"dedup"`
+	// Health controls quarantine of sources that keep failing.
+	Health struct {
+		QuarantineAfter int           `mapstructure:"quarantine_after"`
+		Backoff         time.Duration `mapstructure:"backoff"`
+		MaxBackoff      time.Duration `mapstructure:"max_backoff"`
+	} `mapstructure:"health"`
+	Sources []Source `mapstructure:"sources"`
+	DBPath  string   `mapstructure:"db_path"`
+	// DryRun fetches, scores and selects as usual but stores nothing and
+	// writes the rendered messages to Output ("" or "-" for stdout).
+	DryRun struct {
+		Enabled bool   `mapstructure:"enabled"`
+		Output  string `mapstructure:"output"`
+	} `mapstructure:"dry_run"`
+	// S3 persists a SQLite DBPath between Lambda invocations when Bucket is set.
+	S3 struct {
+		Bucket string `mapstructure:"bucket"`
+		Key    string `mapstructure:"key"`
+	} `mapstructure:"s3"`
+
+	// unknown lists the keys of the file that match no field, by path
+	// (e.g. "sources[2].wieght"); Validate reports them.
+	unknown []string
+}
+
+// Destination is one publishing target. Name defaults to Type and must be
+// unique; it is what delivery records in the store refer to.
+type Destination struct {
+	Name string `mapstructure:"name"`
+	Type string `mapstructure:"type"`
+	// WebhookURL is the webhook of slack, discord and webhook
+	// destinations. When empty it is taken from the type's WebhookEnv
+	// variable, if it has one.
+	WebhookURL string `mapstructure:"webhook_url"`
+	// Secret signs webhook deliveries (defaults to WEBHOOK_SECRET);
+	// MaxAttempts and Backoff control their retries.
+	Secret      string        `mapstructure:"secret"`
+	MaxAttempts int           `mapstructure:"max_attempts"`
+	Backoff     time.Duration `mapstructure:"backoff"`
+	// Server and AccessToken are used by mastodon destinations; the token
+	// defaults to MASTODON_ACCESS_TOKEN.
+	Server      string `mapstructure:"server"`
+	AccessToken string `mapstructure:"access_token"`
+	// Template is a text/template for the message text of telegram and
+	// mastodon destinations; empty keeps the built-in layout.
+	Template string `mapstructure:"template"`
+	// Email configures email digest destinations.
+	Email EmailDigest `mapstructure:"email"`
+}
+
+// EmailDigest is sent on its own CronSpec with the items of the last
+// Window that the destination has not had yet.
+type EmailDigest struct {
+	SMTPAddr string        `mapstructure:"smtp_addr"` // host:port
+	Username string        `mapstructure:"username"`
+	Password string        `mapstructure:"password"` // defaults to SMTP_PASSWORD
+	From     string        `mapstructure:"from"`
+	To       []string      `mapstructure:"to"`
+	Subject  string        `mapstructure:"subject"`
+	GroupBy  string        `mapstructure:"group_by"` // "tag" or "source"
+	CronSpec string        `mapstructure:"cron_spec"`
+	Window   time.Duration `mapstructure:"window"`
+	Limit    int           `mapstructure:"limit"`
+}
+
+// WebhookEnv names the environment variable holding the webhook URL for
+// each webhook-based destination type. Generic webhooks usually differ per
+// destination, so they have none.
+var WebhookEnv = map[string]string{
+	"slack":   "SLACK_WEBHOOK_URL",
+	"discord": "DISCORD_WEBHOOK_URL",
+	"webhook": "",
+}
+
+// Publishers returns the configured destinations with defaults applied.
+func (c Config) Publishers() []Destination {
+	if len(c.Destinations) == 0 {
+		return []Destination{{Name: "telegram", Type: "telegram"}}
 	}
-	Sources []struct {
-		Name   string
-		Type   string
-		URL    string
-		Weight float64
-		Tags   []string
+	out := make([]Destination, len(c.Destinations))
+	for i, d := range c.Destinations {
+		if d.Name == "" {
+			d.Name = d.Type
+		}
+		if d.Type == "email" {
+			e := &d.Email
+			if e.GroupBy == "" {
+				e.GroupBy = "tag"
+			}
+			if e.CronSpec == "" {
+				e.CronSpec = "0 7 * * *"
+			}
+			if e.Window == 0 {
+				e.Window = 24 * time.Hour
+			}
+			if e.Limit == 0 {
+				e.Limit = 50
+			}
+		}
+		out[i] = d
 	}
-	DBPath string
+	return out
 }
 
-func Load() Config {
-	var cfg Config
+type Scheduler struct {
+	CronSpec  string `mapstructure:"cron_spec"`
+	BatchSize int    `mapstructure:"batch_size"`
+	// Daemon mode only; each falls back to CronSpec when empty.
+	FetchCron string `mapstructure:"fetch_cron_spec"`
+	PostCron  string `mapstructure:"post_cron_spec"`
+}
+
+// FetchSpec is the cron spec of the daemon's fetch job.
+func (s Scheduler) FetchSpec() string {
+	if s.FetchCron != "" {
+		return s.FetchCron
+	}
+	return s.CronSpec
+}
+
+// PostSpec is the cron spec of the daemon's post job.
+func (s Scheduler) PostSpec() string {
+	if s.PostCron != "" {
+		return s.PostCron
+	}
+	return s.CronSpec
+}
+
+// Keyword is written either as a bare string or as {term, weight}; a zero
+// weight falls back to scoring.keyword_weight / scoring.negative_weight.
+type Keyword struct {
+	Term   string  `mapstructure:"term"`
+	Weight float64 `mapstructure:"weight"`
+}
+
+type Source struct {
+	Name   string   `mapstructure:"name"`
+	Type   string   `mapstructure:"type"`
+	URL    string   `mapstructure:"url"`
+	Weight float64  `mapstructure:"weight"`
+	Tags   []string `mapstructure:"tags"`
+	// Selectors are only used by "html" sources.
+	Selectors struct {
+		Item    string `mapstructure:"item"`
+		Title   string `mapstructure:"title"`
+		Link    string `mapstructure:"link"`
+		Date    string `mapstructure:"date"`
+		Summary string `mapstructure:"summary"`
+		Image   string `mapstructure:"image"`
+	} `mapstructure:"selectors"`
+}
+
+// Path resolves the config file location: an explicit flag value wins,
+// then CONFIG_PATH, then DefaultPath.
+func Path(flagValue string) string {
+	if flagValue != "" {
+		return flagValue
+	}
+	if p := os.Getenv("CONFIG_PATH"); p != "" {
+		return p
+	}
+	return DefaultPath
+}
 
-	if cfg.Telegram.BotToken == "" {
-		cfg.Telegram.BotToken = os.Getenv("TOKEN")
+// Load reads the YAML file at path on top of the built-in defaults and then
+// applies environment overrides.
+func Load(path string) (Config, error) {
+	cfg := defaults()
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("read config: %w", err)
+	}
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(raw, &doc); err != nil {
+		return cfg, fmt.Errorf("parse %s: %w", path, err)
+	}
+	var md mapstructure.Metadata
+	dec, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           &cfg,
+		Metadata:         &md,
+		WeaklyTypedInput: true,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			stringToKeywordHook,
+		),
+	})
+	if err != nil {
+		return cfg, err
+	}
+	if err := dec.Decode(doc); err != nil {
+		return cfg, fmt.Errorf("decode %s: %w", path, err)
 	}
+	// A misspelt key would otherwise leave its field at the default.
+	cfg.unknown = md.Unused
+	sort.Strings(cfg.unknown)
+
+	if err := applyEnv(&cfg); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// stringToKeywordHook lets keyword lists mix plain strings and maps.
+func stringToKeywordHook(from, to reflect.Type, data interface{}) (interface{}, error) {
+	if to != reflect.TypeOf(Keyword{}) || from.Kind() != reflect.String {
+		return data, nil
+	}
+	return Keyword{Term: data.(string)}, nil
+}
+
+func defaults() Config {
+	var cfg Config
 
-	cfg.Telegram.ChannelID = os.Getenv("CHANNEL_ID")
 	cfg.Telegram.ParseMode = "MarkdownV2"
+	cfg.Telegram.SendInterval = 3 * time.Second
+	cfg.Telegram.MaxAttempts = 4
 
 	// Scheduler
 	cfg.Scheduler.CronSpec = "0 9 * * *" // daily at 09:00
@@ -50,105 +283,101 @@ func Load() Config {
 	cfg.Filters.MaxAgeDays = 21
 	cfg.Filters.MinScore = 0.6
 
-	// Keywords
-	cfg.Keywords.Positive = []string{
-		"kubernetes", "cncf", "k8s", "envoy", "istio", "linkerd",
-		"prometheus", "opentelemetry", "otel", "ebpf", "wasm",
-		"argo", "flux", "helm", "cilium", "kyverno", "trivy",
-		"sigstore", "telemetry", "observability", "supply chain",
-		"slsa", "containerd", "cri-o",
-	}
-	cfg.Keywords.Negative = []string{"marketing webinar", "press release", "sponsored"}
-
-	// Sources
-	cfg.Sources = []struct {
-		Name   string
-		Type   string
-		URL    string
-		Weight float64
-		Tags   []string
-	}{
-		{"CNCF Blog", "rss", "https://www.cncf.io/feed/", 1.0, []string{"news", "foundation", "projects"}},
-		{"Kubernetes Blog", "rss", "https://kubernetes.io/feed.xml", 1.0, []string{"kubernetes", "sig"}},
-		{"OpenTelemetry Blog", "rss", "https://opentelemetry.io/blog/index.xml", 0.9, []string{"otel", "observability"}},
-		{"Envoy Proxy", "rss", "https://blog.envoyproxy.io/feed", 0.8, []string{"envoy", "proxy"}},
-		{"Cadence Workflow", "rss", "https://cadenceworkflow.io/blog/atom.xml", 0.7, []string{"automation", "configuration"}},
-		{"DevStream", "rss", "https://blog.devstream.io/index.xml", 0.7, []string{"devstream"}},
-		{"Kairos Blog", "rss", "https://kairos.io/blog/index.xml", 0.75, []string{"kairos", "edge", "linux"}},
-		{"KCL Blog", "rss", "https://www.kcl-lang.io/blog/rss.xml", 0.72, []string{"kcl", "policy", "language"}},
-		{"Kratix Blog", "rss", "https://docs.kratix.io/blog/rss.xml", 0.73, []string{"kratix", "platform"}},
-		{"KubeDL Blog", "rss", "https://kubedl.io/blog/rss.xml", 0.74, []string{"kubedl", "ml", "kubernetes"}},
-		{"KubeEdge Blog", "rss", "https://kubeedge.io/blog/rss.xml", 0.82, []string{"kubeedge", "edge", "iot"}},
-		{"Konstruct Blog", "rss", "https://blog.konstruct.io/rss/", 0.71, []string{"konstruct", "infrastructure"}},
-		{"KusionStack Blog", "rss", "https://medium.com/feed/@kusionstack", 0.72, []string{"kusionstack", "iac"}},
-		{"ManageIQ Blog", "rss", "https://www.manageiq.org/feed.xml", 0.77, []string{"manageiq", "automation"}},
-		{"OpenStack Blog", "rss", "https://www.openstack.org/blog/feed/", 0.92, []string{"openstack", "cloud"}},
-		{"OpenTofu Blog", "rss", "https://opentofu.org/blog/rss.xml", 0.86, []string{"opentofu", "terraform", "iac"}},
-		{"OpenYurt Blog", "rss", "https://openyurt.io/blog/rss.xml", 0.78, []string{"openyurt", "edge"}},
-		{"Pulumi Blog", "rss", "https://www.pulumi.com/blog/rss.xml", 0.91, []string{"pulumi", "iac", "cloud"}},
-		{"Salt Project Blog", "rss", "https://saltproject.io/blog/index.xml", 0.87, []string{"saltstack", "automation"}},
-		{"Shifu Technical Blogs", "rss", "https://shifu.dev/technical-blogs/rss.xml", 0.71, []string{"shifu", "edge"}},
-		{"Shifu News", "rss", "https://shifu.dev/news/rss.xml", 0.71, []string{"shifu", "news"}},
-		{"HashiCorp Blog", "rss", "https://www.hashicorp.com/blog/feed.xml", 0.98, []string{"hashicorp", "terraform", "vault"}},
-		{"Updatecli Blog", "rss", "https://www.updatecli.io/blog/index.xml", 0.72, []string{"updatecli", "automation"}},
-		{"Dragonfly (D7y) Blog", "rss", "https://d7y.io/blog/rss.xml", 0.76, []string{"dragonfly", "distribution"}},
-		{"Cerbos Blog", "rss", "https://www.cerbos.dev/rss", 0.74, []string{"cerbos", "authorization"}},
-		{"Confidential Containers Blog", "rss", "https://confidentialcontainers.org/blog/index.xml", 0.77, []string{"confidential-containers", "security"}},
-		{"Falco Blog", "rss", "https://falco.org/blog/feed.xml", 0.88, []string{"falco", "security"}},
-		{"in-toto Blog", "rss", "https://in-toto.io/blog/index.xml", 0.76, []string{"in-toto", "supply-chain"}},
-		{"Keycloak Blog", "rss", "https://www.keycloak.org/rss.xml", 0.89, []string{"keycloak", "identity"}},
-		{"KubeArmor Blog", "rss", "https://kubearmor.io/blog/rss.xml", 0.77, []string{"kubearmor", "security"}},
-		{"Kubescape New Feed", "rss", "https://kubescape.io/feed_rss_created.xml", 0.82, []string{"kubescape", "security"}},
-		{"Kubescape Updates", "rss", "https://kubescape.io/feed_rss_updated.xml", 0.82, []string{"kubescape", "security"}},
-		{"Kubewarden Blog", "rss", "https://www.kubewarden.io/blog/index.xml", 0.8, []string{"kubewarden", "policy"}},
-		{"Kyverno Blog", "rss", "https://kyverno.io/blog/index.xml", 0.87, []string{"kyverno", "policy"}},
-		{"AccuKnox Blog", "rss", "https://accuknox.com/feed", 0.76, []string{"accuknox", "security"}},
-		{"Open Policy Containers Blog", "rss", "https://openpolicycontainers.com/blog/rss.xml", 0.72, []string{"policy", "containers"}},
-		{"OpenFGA Blog", "rss", "https://openfga.dev/blog/rss.xml", 0.73, []string{"openfga", "authorization"}},
-		{"Paladin Cloud Blog", "rss", "https://paladincloud.io/feed/", 0.74, []string{"paladincloud", "security"}},
-		{"Paralus Blog", "rss", "https://www.paralus.io/blog/rss.xml", 0.73, []string{"paralus", "kubernetes", "networking"}},
-		{"Ratify Blog", "rss", "https://ratify.dev/blog/rss.xml", 0.7, []string{"ratify", "supply-chain", "security"}},
-		{"SecureCodeBox Blog", "rss", "https://www.securecodebox.io/blog/rss.xml", 0.7, []string{"security", "testing", "kubernetes"}},
-		{"Sigstore Blog", "rss", "https://blog.sigstore.dev/index.xml", 0.9, []string{"sigstore", "security", "supply-chain"}},
-		{"Sonobuoy Blog", "rss", "https://sonobuoy.io/blog/feed.xml", 0.9, []string{"sonobuoy", "testing", "kubernetes"}},
-		{"StackRox Blog", "rss", "https://www.stackrox.io/rss.xml", 0.9, []string{"security", "containers", "kubernetes"}},
-		{"Teleport Blog", "rss", "https://goteleport.com/blog/rss.xml", 0.8, []string{"teleport", "security", "identity"}},
-		{"Varmor Blog", "rss", "https://www.varmor.org/blog/rss.xml", 0.7, []string{"varmor", "security", "kubernetes"}},
-		{"Pinniped Blog", "rss", "https://pinniped.dev/blog/index.xml", 0.7, []string{"pinniped", "security", "identity"}},
-		{"JuiceFS Blog", "rss", "https://juicefs.com/en/blog/latest/feed/", 0.8, []string{"storage", "distributed-systems", "filesystem"}},
-		{"MinIO Blog", "rss", "https://blog.min.io/rss/", 0.9, []string{"minio", "storage", "s3"}},
-		{"MooseFS Blog", "rss", "https://moosefs.com/feed/", 0.7, []string{"storage", "filesystem"}},
-		{"ORAS Blog", "rss", "https://oras.land/blog/rss.xml", 0.7, []string{"oras", "artifacts", "registry"}},
-		{"Rook Blog", "rss", "https://blog.rook.io/feed", 0.8, []string{"rook", "storage", "ceph"}},
-		{"Velero Blog", "rss", "https://velero.io/blog/index.xml", 0.8, []string{"backup", "recovery", "kubernetes"}},
-		{"CNCF Vineyard Blog", "rss", "https://medium.com/feed/cncf-vineyard", 0.7, []string{"vineyard", "data", "cncf"}},
-		{"Layer5 Blog", "rss", "https://layer5.io/blog/feed.xml", 0.7, []string{"service-mesh", "istio", "kubernetes"}},
-		{"Layer5 News", "rss", "https://layer5.io/news/feed.xml", 0.7, []string{"service-mesh", "community", "cncf"}},
-		{"DeisLabs Blog", "rss", "https://deislabs.io/posts/index.xml", 0.7, []string{"deislabs", "cloud-native", "experiments"}},
-		{"Kuasar Blog", "rss", "https://kuasar.io/blog/index.xml", 0.7, []string{"wasm", "containers", "runtime"}},
-		{"Podman Desktop Blog", "rss", "https://podman-desktop.io/blog/rss.xml", 0.8, []string{"podman", "desktop", "containers"}},
-		{"Podman Blog", "rss", "https://blog.podman.io/feed/", 0.8, []string{"podman", "containers", "linux"}},
-		{"Antrea Blog", "rss", "https://antrea.io/blog/feed.xml", 0.7, []string{"antrea", "networking", "kubernetes"}},
-		{"Cilium Blog", "rss", "https://cilium.io/blog/rss.xml", 0.9, []string{"cilium", "ebpf", "networking"}},
-		{"Kube-OVN Blog", "rss", "https://www.kube-ovn.io/news/rss.xml", 0.7, []string{"networking", "ovn", "kubernetes"}},
-		{"Red Hat Blog", "rss", "https://www.redhat.com/en/rss/blog", 0.9, []string{"redhat", "open-source", "enterprise"}},
-		{"Crossplane Blog", "rss", "https://blog.crossplane.io/rss/", 0.8, []string{"crossplane", "infrastructure", "control-plane"}},
-		{"Fluid Blog", "rss", "https://fluid-cloudnative.github.io/blog/rss.xml", 0.7, []string{"fluid", "data", "kubernetes"}},
-		{"Project Hami Blog", "rss", "https://project-hami.io/blog/rss.xml", 0.7, []string{"hami", "gpu", "scheduling"}},
-		{"Karmada Blog", "rss", "https://karmada.io/blog/rss.xml", 0.8, []string{"karmada", "multi-cluster", "kubernetes"}},
-		{"KCP Blog", "rss", "https://www.kcp.io/blog/index.xml", 0.7, []string{"kcp", "multi-tenancy", "kubernetes"}},
-		{"k0s Project Blog", "rss", "https://medium.com/feed/k0sproject", 0.7, []string{"k0s", "lightweight", "kubernetes"}},
-		{"KEDA Blog", "rss", "https://keda.sh/blog/index.xml", 0.8, []string{"keda", "autoscaling", "kubernetes"}},
-		{"Kestra Blog", "rss", "https://kestra.io/rss.xml", 0.7, []string{"kestra", "orchestration", "workflow"}},
-		{"Knative Blog (Created)", "rss", "https://knative.dev/blog/feed_rss_created.xml", 0.9, []string{"knative", "serverless", "kubernetes"}},
-		{"Knative Blog (Updated)", "rss", "https://knative.dev/blog/feed_rss_updated.xml", 0.9, []string{"knative", "serverless", "kubernetes"}},
-		{"Koordinator Blog", "rss", "https://koordinator.sh/blog/rss.xml", 0.7, []string{"koordinator", "scheduling", "performance"}},
-		{"Kube-Green Blog", "rss", "https://kube-green.dev/blog/rss.xml", 0.7, []string{"kube-green", "sustainability", "kubernetes"}},
-	}
-
-	// DB path
-	cfg.DBPath = os.Getenv("DB_PATH")
+	// Scoring
+	cfg.Scoring.SourceWeight = 0.4
+	cfg.Scoring.KeywordWeight = 0.15
+	cfg.Scoring.NegativeWeight = 0.3
+	cfg.Scoring.TitleBoost = 2
+	cfg.Scoring.HalfLife = 7 * 24 * time.Hour
+
+	// Fetch
+	cfg.Fetch.Concurrency = 8
+	cfg.Fetch.Timeout = 30 * time.Second
+	cfg.Fetch.OGImage = true
+
+	// Dedup
+	cfg.Dedup.Enabled = true
+	cfg.Dedup.MaxDistance = 11
+	cfg.Dedup.Window = 14 * 24 * time.Hour
+
+	// Health
+	cfg.Health.QuarantineAfter = 5
+	cfg.Health.Backoff = 6 * time.Hour
+	cfg.Health.MaxBackoff = 7 * 24 * time.Hour
+
+	cfg.S3.Key = "cncg-bot/data.db"
 
 	return cfg
+}
 
+// applyEnv layers environment variables over the file values. Secrets
+// (TOKEN, CHANNEL_ID) are expected to come from here rather than the file.
+func applyEnv(cfg *Config) error {
+	if v := os.Getenv("TOKEN"); v != "" {
+		cfg.Telegram.BotToken = v
+	}
+	if v := os.Getenv("CHANNEL_ID"); v != "" {
+		cfg.Telegram.ChannelID = v
+	}
+	if v := os.Getenv("PARSE_MODE"); v != "" {
+		cfg.Telegram.ParseMode = v
+	}
+	if v := os.Getenv("CRON_SPEC"); v != "" {
+		cfg.Scheduler.CronSpec = v
+	}
+	if v := os.Getenv("BATCH_SIZE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("BATCH_SIZE: %w", err)
+		}
+		cfg.Scheduler.BatchSize = n
+	}
+	if v := os.Getenv("MAX_AGE_DAYS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("MAX_AGE_DAYS: %w", err)
+		}
+		cfg.Filters.MaxAgeDays = n
+	}
+	if v := os.Getenv("MIN_SCORE"); v != "" {
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("MIN_SCORE: %w", err)
+		}
+		cfg.Filters.MinScore = f
+	}
+	for i, d := range cfg.Destinations {
+		if env := WebhookEnv[d.Type]; env != "" && d.WebhookURL == "" {
+			cfg.Destinations[i].WebhookURL = os.Getenv(env)
+		}
+		if d.Type == "mastodon" && d.AccessToken == "" {
+			cfg.Destinations[i].AccessToken = os.Getenv("MASTODON_ACCESS_TOKEN")
+		}
+		if d.Type == "email" && d.Email.Password == "" {
+			cfg.Destinations[i].Email.Password = os.Getenv("SMTP_PASSWORD")
+		}
+		if d.Type == "webhook" && d.Secret == "" {
+			cfg.Destinations[i].Secret = os.Getenv("WEBHOOK_SECRET")
+		}
+	}
+	if v := os.Getenv("DB_PATH"); v != "" {
+		cfg.DBPath = v
+	}
+	if v := os.Getenv("DRY_RUN"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("DRY_RUN: %w", err)
+		}
+		cfg.DryRun.Enabled = b
+	}
+	if v := os.Getenv("DRY_RUN_OUTPUT"); v != "" {
+		cfg.DryRun.Output = v
+	}
+	if v := os.Getenv("S3_BUCKET"); v != "" {
+		cfg.S3.Bucket = v
+	}
+	if v := os.Getenv("S3_KEY"); v != "" {
+		cfg.S3.Key = v
+	}
+	return nil
 }
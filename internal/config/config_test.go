@@ -0,0 +1,157 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, yaml string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	path := writeConfig(t, `
+telegram:
+  channel_id: "-1001"
+scheduler:
+  batch_size: 3
+fetch:
+  timeout: 45s
+health:
+  max_backoff: "72h"
+keywords:
+  positive: ["kubernetes", {term: "ebpf", weight: 0.5}]
+sources:
+  - name: K8s
+    type: rss
+    url: https://kubernetes.io/feed.xml
+    weight: "0.9"
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Telegram.ChannelID != "-1001" || cfg.Scheduler.BatchSize != 3 {
+		t.Errorf("file values not applied: %+v %+v", cfg.Telegram, cfg.Scheduler)
+	}
+	if cfg.Fetch.Timeout != 45*time.Second || cfg.Health.MaxBackoff != 72*time.Hour {
+		t.Errorf("durations = %v, %v; want 45s, 72h", cfg.Fetch.Timeout, cfg.Health.MaxBackoff)
+	}
+	if cfg.Filters.MinScore != 0.6 || cfg.Telegram.ParseMode != "MarkdownV2" {
+		t.Errorf("defaults lost: min_score=%v parse_mode=%q", cfg.Filters.MinScore, cfg.Telegram.ParseMode)
+	}
+	want := []Keyword{{Term: "kubernetes"}, {Term: "ebpf", Weight: 0.5}}
+	if len(cfg.Keywords.Positive) != 2 || cfg.Keywords.Positive[0] != want[0] || cfg.Keywords.Positive[1] != want[1] {
+		t.Errorf("keywords = %+v, want %+v", cfg.Keywords.Positive, want)
+	}
+	if len(cfg.Sources) != 1 || cfg.Sources[0].Weight != 0.9 {
+		t.Errorf("sources = %+v", cfg.Sources)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := []struct {
+		name, yaml, want string
+	}{
+		{"bad yaml", "telegram: [", "parse"},
+		{"bad duration", "fetch:\n  timeout: soon\n", "decode"},
+		{"bad type", "scheduler:\n  batch_size: many\n", "decode"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Load(writeConfig(t, tt.yaml)); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Load = %v, want a %s error", err, tt.want)
+			}
+		})
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Load(missing) = %v, want os.ErrNotExist", err)
+	}
+}
+
+func TestLoadUnknownKeys(t *testing.T) {
+	path := writeConfig(t, `
+telegram:
+  bot_token: "1:a"
+  channel_id: "-1001"
+filter:
+  min_score: 0.9
+sources:
+  - name: K8s
+    type: rss
+    url: https://kubernetes.io/feed.xml
+    wieght: 0.9
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ve *ValidationError
+	if err := cfg.Validate(); !errors.As(err, &ve) {
+		t.Fatalf("Validate = %v, want the unknown keys reported", err)
+	}
+	var got []string
+	for _, fe := range ve.Errors {
+		if fe.Msg == "unknown key" {
+			got = append(got, fe.Path)
+		}
+	}
+	if strings.Join(got, " ") != "filter sources[0].wieght" {
+		t.Errorf("unknown keys = %v, want filter and sources[0].wieght", got)
+	}
+}
+
+// TestLoadShippedConfig keeps config.yaml in step with the Config fields.
+func TestLoadShippedConfig(t *testing.T) {
+	cfg, err := Load("../../config.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.unknown) > 0 {
+		t.Errorf("config.yaml has unknown keys: %v", cfg.unknown)
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	path := writeConfig(t, `
+destinations:
+  - type: slack
+  - type: mastodon
+    server: https://hachyderm.io
+`)
+	t.Setenv("TOKEN", "1:env")
+	t.Setenv("BATCH_SIZE", "7")
+	t.Setenv("MIN_SCORE", "0.25")
+	t.Setenv("DRY_RUN", "true")
+	t.Setenv("SLACK_WEBHOOK_URL", "https://hooks.slack.com/x")
+	t.Setenv("MASTODON_ACCESS_TOKEN", "tok")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Telegram.BotToken != "1:env" || cfg.Scheduler.BatchSize != 7 || cfg.Filters.MinScore != 0.25 || !cfg.DryRun.Enabled {
+		t.Errorf("env not applied: token=%q batch=%d min=%v dry=%v",
+			cfg.Telegram.BotToken, cfg.Scheduler.BatchSize, cfg.Filters.MinScore, cfg.DryRun.Enabled)
+	}
+	if cfg.Destinations[0].WebhookURL != "https://hooks.slack.com/x" || cfg.Destinations[1].AccessToken != "tok" {
+		t.Errorf("destination secrets not applied: %+v", cfg.Destinations)
+	}
+
+	for _, env := range []string{"BATCH_SIZE", "MAX_AGE_DAYS", "MIN_SCORE", "DRY_RUN"} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv(env, "lots")
+			if _, err := Load(path); err == nil || !strings.HasPrefix(err.Error(), env) {
+				t.Errorf("Load with %s=lots = %v, want an error naming it", env, err)
+			}
+		})
+	}
+}
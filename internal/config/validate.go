@@ -0,0 +1,341 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/mail"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/robfig/cron/v3"
+)
+
+// SourceTypes lists the source types the pipeline knows how to fetch.
+var SourceTypes = map[string]bool{
+	"rss":  true,
+	"html": true,
+}
+
+// DestinationTypes lists the publisher types accepted in destinations[].type.
+var DestinationTypes = map[string]bool{
+	"telegram": true,
+	"slack":    true,
+	"discord":  true,
+	"mastodon": true,
+	"webhook":  true,
+	"email":    true,
+}
+
+// ParseModes lists the Telegram parse modes accepted in telegram.parse_mode.
+var ParseModes = map[string]bool{
+	"MarkdownV2": true,
+	"HTML":       true,
+	"":           true, // plain text
+}
+
+// FieldError is a single problem found in the config, keyed by its path
+// in config.yaml (e.g. "sources[3].weight").
+type FieldError struct {
+	Path string
+	Msg  string
+}
+
+func (e FieldError) Error() string { return e.Path + ": " + e.Msg }
+
+// ValidationError collects every FieldError found by Validate.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "invalid config (%d problems):", len(e.Errors))
+	for _, fe := range e.Errors {
+		b.WriteString("\n  - ")
+		b.WriteString(fe.Error())
+	}
+	return b.String()
+}
+
+// Without drops the errors under prefix (e.g. "telegram.") for commands
+// that never use that section. It returns nil when nothing is left.
+func (e *ValidationError) Without(prefix string) error {
+	var kept []FieldError
+	for _, fe := range e.Errors {
+		if !strings.HasPrefix(fe.Path, prefix) {
+			kept = append(kept, fe)
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: kept}
+}
+
+func (e *ValidationError) add(path, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, FieldError{Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks the whole config and reports every problem at once.
+// It returns nil or a *ValidationError.
+func (c Config) Validate() error {
+	ve := &ValidationError{}
+
+	for _, key := range c.unknown {
+		ve.add(key, "unknown key")
+	}
+
+	// Telegram (only needed when a telegram destination is configured)
+	if c.usesDestination("telegram") {
+		if c.Telegram.BotToken == "" {
+			ve.add("telegram.bot_token", "is required (set TOKEN)")
+		}
+		if c.Telegram.ChannelID == "" {
+			ve.add("telegram.channel_id", "is required (set CHANNEL_ID)")
+		} else if _, err := strconv.ParseInt(c.Telegram.ChannelID, 10, 64); err != nil {
+			ve.add("telegram.channel_id", "must be a numeric chat id, got %q", c.Telegram.ChannelID)
+		}
+		if !ParseModes[c.Telegram.ParseMode] {
+			ve.add("telegram.parse_mode", "unsupported parse mode %q (want MarkdownV2, HTML or empty)", c.Telegram.ParseMode)
+		}
+		if c.Telegram.SendInterval < 0 {
+			ve.add("telegram.send_interval", "must be >= 0, got %s", c.Telegram.SendInterval)
+		}
+		if c.Telegram.MaxAttempts < 1 {
+			ve.add("telegram.max_attempts", "must be >= 1, got %d", c.Telegram.MaxAttempts)
+		}
+	}
+
+	// Destinations
+	dests := map[string]int{}
+	for i, d := range c.Publishers() {
+		p := fmt.Sprintf("destinations[%d]", i)
+		if !DestinationTypes[d.Type] {
+			ve.add(p+".type", "unsupported destination type %q", d.Type)
+		}
+		if j, dup := dests[d.Name]; dup {
+			ve.add(p+".name", "%q duplicates destinations[%d]", d.Name, j)
+		} else {
+			dests[d.Name] = i
+		}
+		if env, ok := WebhookEnv[d.Type]; ok {
+			// The URL is a credential, so it is not echoed back. Slack
+			// and Discord only hand out https webhooks.
+			u, err := url.Parse(d.WebhookURL)
+			switch {
+			case d.WebhookURL == "" && env != "":
+				ve.add(p+".webhook_url", "is required (set %s)", env)
+			case d.WebhookURL == "":
+				ve.add(p+".webhook_url", "is required")
+			case err != nil || u.Host == "":
+				ve.add(p+".webhook_url", "must be an absolute URL")
+			case u.Scheme != "https" && (d.Type != "webhook" || u.Scheme != "http"):
+				ve.add(p+".webhook_url", "unsupported scheme %q", u.Scheme)
+			}
+		}
+		if d.Type == "webhook" {
+			if d.Secret == "" {
+				ve.add(p+".secret", "is required (set WEBHOOK_SECRET)")
+			}
+			if d.MaxAttempts < 0 {
+				ve.add(p+".max_attempts", "must be >= 0, got %d", d.MaxAttempts)
+			}
+			if d.Backoff < 0 {
+				ve.add(p+".backoff", "must be >= 0, got %s", d.Backoff)
+			}
+		}
+		if d.Type == "email" {
+			validateEmail(ve, p+".email", d.Email)
+		}
+		if d.Type == "mastodon" {
+			if u, err := url.Parse(d.Server); d.Server == "" || err != nil || u.Scheme != "https" || u.Host == "" {
+				ve.add(p+".server", "must be the instance's https URL, got %q", d.Server)
+			}
+			if d.AccessToken == "" {
+				ve.add(p+".access_token", "is required (set MASTODON_ACCESS_TOKEN)")
+			}
+		}
+	}
+
+	// Scheduler
+	if _, err := cron.ParseStandard(c.Scheduler.CronSpec); err != nil {
+		ve.add("scheduler.cron_spec", "%v", err)
+	}
+	if c.Scheduler.FetchCron != "" {
+		if _, err := cron.ParseStandard(c.Scheduler.FetchCron); err != nil {
+			ve.add("scheduler.fetch_cron_spec", "%v", err)
+		}
+	}
+	if c.Scheduler.PostCron != "" {
+		if _, err := cron.ParseStandard(c.Scheduler.PostCron); err != nil {
+			ve.add("scheduler.post_cron_spec", "%v", err)
+		}
+	}
+	if c.Scheduler.BatchSize <= 0 {
+		ve.add("scheduler.batch_size", "must be > 0, got %d", c.Scheduler.BatchSize)
+	}
+
+	// Filters
+	if c.Filters.MaxAgeDays <= 0 {
+		ve.add("filters.max_age_days", "must be > 0, got %d", c.Filters.MaxAgeDays)
+	}
+	if c.Filters.MinScore < 0 || c.Filters.MinScore > 1 {
+		ve.add("filters.min_score", "must be between 0 and 1, got %g", c.Filters.MinScore)
+	}
+
+	// Fetch
+	if c.Fetch.Concurrency <= 0 {
+		ve.add("fetch.concurrency", "must be > 0, got %d", c.Fetch.Concurrency)
+	}
+	if c.Fetch.Timeout <= 0 {
+		ve.add("fetch.timeout", "must be a positive duration, got %s", c.Fetch.Timeout)
+	}
+
+	// Dedup
+	if c.Dedup.Enabled {
+		if c.Dedup.MaxDistance < 0 || c.Dedup.MaxDistance > 64 {
+			ve.add("dedup.max_distance", "must be between 0 and 64, got %d", c.Dedup.MaxDistance)
+		}
+		if c.Dedup.Window <= 0 {
+			ve.add("dedup.window", "must be a positive duration, got %s", c.Dedup.Window)
+		}
+	}
+
+	// Health
+	if c.Health.QuarantineAfter < 0 {
+		ve.add("health.quarantine_after", "must be >= 0, got %d", c.Health.QuarantineAfter)
+	}
+	if c.Health.QuarantineAfter > 0 && c.Health.Backoff <= 0 {
+		ve.add("health.backoff", "must be a positive duration when quarantine is enabled")
+	}
+	if c.Health.MaxBackoff < c.Health.Backoff {
+		ve.add("health.max_backoff", "must be >= health.backoff (%s), got %s", c.Health.Backoff, c.Health.MaxBackoff)
+	}
+
+	// Keywords
+	for i, kw := range c.Keywords.Positive {
+		validateKeyword(ve, fmt.Sprintf("keywords.positive[%d]", i), kw)
+	}
+	for i, kw := range c.Keywords.Negative {
+		validateKeyword(ve, fmt.Sprintf("keywords.negative[%d]", i), kw)
+	}
+
+	// Scoring
+	for _, f := range []struct {
+		path string
+		v    float64
+	}{
+		{"scoring.source_weight", c.Scoring.SourceWeight},
+		{"scoring.keyword_weight", c.Scoring.KeywordWeight},
+		{"scoring.negative_weight", c.Scoring.NegativeWeight},
+		{"scoring.title_boost", c.Scoring.TitleBoost},
+	} {
+		if f.v < 0 {
+			ve.add(f.path, "must be >= 0, got %g", f.v)
+		}
+	}
+	if c.Scoring.HalfLife < 0 {
+		ve.add("scoring.half_life", "must be >= 0, got %s", c.Scoring.HalfLife)
+	}
+
+	// Storage
+	if c.DBPath == "" {
+		ve.add("db_path", "is required (set DB_PATH)")
+	}
+	if c.S3.Bucket != "" && c.S3.Key == "" {
+		ve.add("s3.key", "is required when s3.bucket is set")
+	}
+
+	// Sources
+	if len(c.Sources) == 0 {
+		ve.add("sources", "at least one source is required")
+	}
+	seen := map[string]int{}
+	for i, s := range c.Sources {
+		p := fmt.Sprintf("sources[%d]", i)
+		if strings.TrimSpace(s.Name) == "" {
+			ve.add(p+".name", "is required")
+		} else if j, dup := seen[s.Name]; dup {
+			ve.add(p+".name", "%q duplicates sources[%d]", s.Name, j)
+		} else {
+			seen[s.Name] = i
+		}
+		if !SourceTypes[s.Type] {
+			ve.add(p+".type", "unsupported source type %q", s.Type)
+		}
+		if s.Type == "html" {
+			if s.Selectors.Item == "" {
+				ve.add(p+".selectors.item", "is required for html sources")
+			}
+			if s.Selectors.Title == "" {
+				ve.add(p+".selectors.title", "is required for html sources")
+			}
+		}
+		if s.URL == "" {
+			ve.add(p+".url", "is required")
+		} else if u, err := url.Parse(s.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			ve.add(p+".url", "must be an absolute http(s) URL, got %q", s.URL)
+		}
+		if s.Weight < 0 {
+			ve.add(p+".weight", "must be >= 0, got %g", s.Weight)
+		}
+		for j, t := range s.Tags {
+			if strings.TrimSpace(t) == "" {
+				ve.add(fmt.Sprintf("%s.tags[%d]", p, j), "is empty")
+			}
+		}
+	}
+
+	if len(ve.Errors) > 0 {
+		return ve
+	}
+	return nil
+}
+
+func (c Config) usesDestination(typ string) bool {
+	for _, d := range c.Publishers() {
+		if d.Type == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func validateEmail(ve *ValidationError, p string, e EmailDigest) {
+	if _, _, err := net.SplitHostPort(e.SMTPAddr); err != nil {
+		ve.add(p+".smtp_addr", "must be host:port, got %q", e.SMTPAddr)
+	}
+	if _, err := mail.ParseAddress(e.From); err != nil {
+		ve.add(p+".from", "invalid address %q", e.From)
+	}
+	if len(e.To) == 0 {
+		ve.add(p+".to", "needs at least one recipient")
+	}
+	for j, to := range e.To {
+		if _, err := mail.ParseAddress(to); err != nil {
+			ve.add(fmt.Sprintf("%s.to[%d]", p, j), "invalid address %q", to)
+		}
+	}
+	if e.GroupBy != "tag" && e.GroupBy != "source" {
+		ve.add(p+".group_by", "must be tag or source, got %q", e.GroupBy)
+	}
+	if _, err := cron.ParseStandard(e.CronSpec); err != nil {
+		ve.add(p+".cron_spec", "%v", err)
+	}
+	if e.Window <= 0 {
+		ve.add(p+".window", "must be > 0, got %s", e.Window)
+	}
+	if e.Limit <= 0 {
+		ve.add(p+".limit", "must be > 0, got %d", e.Limit)
+	}
+}
+
+func validateKeyword(ve *ValidationError, path string, kw Keyword) {
+	if strings.TrimSpace(kw.Term) == "" {
+		ve.add(path+".term", "is empty")
+	}
+	if kw.Weight < 0 {
+		ve.add(path+".weight", "must be >= 0, got %g", kw.Weight)
+	}
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func validConfig() Config {
+	cfg := defaults()
+	cfg.Telegram.BotToken = "123:abc"
+	cfg.Telegram.ChannelID = "-1001234"
+	cfg.DBPath = "file:data.db"
+	cfg.Sources = []Source{{Name: "Kubernetes Blog", Type: "rss", URL: "https://kubernetes.io/feed.xml", Weight: 1}}
+	return cfg
+}
+
+func TestValidateDefaultsWithSecrets(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatalf("Validate() = %v, want nil", err)
+	}
+}
+
+func TestValidateCollectsEveryError(t *testing.T) {
+	cfg := validConfig()
+	cfg.Telegram.ChannelID = "@channel"
+	cfg.Scheduler.BatchSize = 0
+	cfg.Filters.MinScore = 2
+	cfg.Sources = append(cfg.Sources,
+		Source{Name: "Kubernetes Blog", Type: "atom", URL: "ftp://example.com", Weight: -1},
+		Source{Name: "Scraped", Type: "html", URL: "https://example.com"},
+	)
+
+	err := cfg.Validate()
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("Validate() = %v, want *ValidationError", err)
+	}
+	want := []string{
+		"telegram.channel_id",
+		"scheduler.batch_size",
+		"filters.min_score",
+		"sources[1].name",
+		"sources[1].type",
+		"sources[1].url",
+		"sources[1].weight",
+		"sources[2].selectors.item",
+		"sources[2].selectors.title",
+	}
+	var got []string
+	for _, fe := range ve.Errors {
+		got = append(got, fe.Path)
+	}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("paths = %v\nwant %v", got, want)
+	}
+	if !strings.Contains(err.Error(), "(9 problems)") {
+		t.Errorf("Error() = %q, want the problem count", err.Error())
+	}
+}
+
+func TestValidationErrorWithout(t *testing.T) {
+	cfg := validConfig()
+	cfg.Telegram.BotToken = ""
+	cfg.Telegram.ChannelID = ""
+
+	var ve *ValidationError
+	if !errors.As(cfg.Validate(), &ve) || len(ve.Errors) != 2 {
+		t.Fatalf("Validate() = %v, want two telegram errors", ve)
+	}
+	if err := ve.Without("telegram."); err != nil {
+		t.Errorf("Without(telegram.) = %v, want nil", err)
+	}
+}
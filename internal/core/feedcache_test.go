@@ -0,0 +1,105 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestFeedCache runs the pipeline against a feed that answers 304 to the
+// validators it handed out. The validators are stored with the items, so a
+// run whose inserts fail fetches the feed in full again next time.
+func TestFeedCache(t *testing.T) {
+	var mu sync.Mutex
+	version, posts := 1, 1
+	var sent []string // If-None-Match of each request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, r.Header.Get("If-None-Match"))
+		etag := fmt.Sprintf(`"v%d"`, version)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>t</title>`)
+		for i := 1; i <= posts; i++ {
+			fmt.Fprintf(w, `<item><title>Kubernetes post %d</title><link>https://example.com/%d</link><pubDate>%s</pubDate></item>`,
+				i, i, time.Now().UTC().Format(time.RFC1123Z))
+		}
+		fmt.Fprint(w, `</channel></rss>`)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	db := openTestStore(t)
+	p := &Pipeline{
+		DB:      db,
+		Filters: Filters{MaxAgeDays: 21},
+		Sources: []SourceCfg{{Name: "test", Type: "rss", URL: srv.URL, Weight: 1}},
+	}
+	run := func() {
+		t.Helper()
+		if err := p.RunOnce(ctx); err != nil {
+			t.Fatal(err)
+		}
+	}
+	count := func() (n int) {
+		t.Helper()
+		if err := db.DB.QueryRowContext(ctx, `SELECT COUNT(*) FROM items`).Scan(&n); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	etag := func() string {
+		t.Helper()
+		caches, err := db.FeedCaches(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return caches[srv.URL].ETag
+	}
+
+	run() // 200, v1
+	run() // 304
+	if n, tag := count(), etag(); n != 1 || tag != `"v1"` {
+		t.Fatalf("after two runs: %d items, etag %s; want 1 and \"v1\"", n, tag)
+	}
+	states, err := db.SourceStates(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st := states["test"]; st.ConsecutiveFailures != 0 {
+		t.Errorf("a 304 counted as a failure: %+v", st)
+	}
+
+	// The feed changes but nothing can be stored.
+	mu.Lock()
+	version, posts = 2, 2
+	mu.Unlock()
+	if _, err := db.DB.ExecContext(ctx, `CREATE TRIGGER no_insert BEFORE INSERT ON items BEGIN SELECT RAISE(FAIL, 'disk full'); END`); err != nil {
+		t.Fatal(err)
+	}
+	run()
+	if tag := etag(); tag != `"v1"` {
+		t.Errorf("etag after failed inserts = %s, want \"v1\" kept", tag)
+	}
+
+	if _, err := db.DB.ExecContext(ctx, `DROP TRIGGER no_insert`); err != nil {
+		t.Fatal(err)
+	}
+	run()
+	if n, tag := count(), etag(); n != 2 || tag != `"v2"` {
+		t.Errorf("after recovery: %d items, etag %s; want 2 and \"v2\"", n, tag)
+	}
+
+	want := fmt.Sprint([]string{"", `"v1"`, `"v1"`, `"v1"`})
+	if got := fmt.Sprint(sent); got != want {
+		t.Errorf("If-None-Match sent = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,102 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/LibenHailu/cncg-bot/internal/fetch"
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+const (
+	defaultConcurrency   = 8
+	defaultSourceTimeout = 30 * time.Second
+
+	// ogImageBudget bounds all og:image lookups of one run together.
+	ogImageBudget = time.Minute
+)
+
+var errUnsupportedType = errors.New("unsupported source type")
+
+// fetchResult is the outcome for one source; results keep the order of
+// Pipeline.Sources so the merge after fetching is deterministic.
+type fetchResult struct {
+	src        SourceCfg
+	items      []fetch.Item
+	validators fetch.Validators
+	elapsed    time.Duration
+	err        error
+}
+
+// fetchAll fetches sources with at most Concurrency requests in flight,
+// each bounded by SourceTimeout (and by ctx's own deadline).
+func (p *Pipeline) fetchAll(ctx context.Context, sources []SourceCfg, caches map[string]store.FeedCache) []fetchResult {
+	workers := p.Concurrency
+	if workers <= 0 {
+		workers = defaultConcurrency
+	}
+	timeout := p.SourceTimeout
+	if timeout <= 0 {
+		timeout = defaultSourceTimeout
+	}
+
+	results := make([]fetchResult, len(sources))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				src := sources[i]
+				sctx, cancel := context.WithTimeout(ctx, timeout)
+				fc := caches[src.URL]
+				start := time.Now()
+				items, v, err := fetchSource(sctx, src, fetch.Validators{ETag: fc.ETag, LastModified: fc.LastModified})
+				cancel()
+				results[i] = fetchResult{src: src, items: items, validators: v, elapsed: time.Since(start), err: err}
+			}
+		}()
+	}
+	for i := range sources {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+// ProbeResult is the outcome of fetching one source with Probe.
+type ProbeResult struct {
+	Source  SourceCfg
+	Items   int
+	Elapsed time.Duration
+	Err     error
+}
+
+// Probe fetches every source in full (no conditional GET, no quarantine)
+// and reports the outcome without writing to the store.
+func (p *Pipeline) Probe(ctx context.Context) []ProbeResult {
+	results := p.fetchAll(ctx, p.Sources, nil)
+	out := make([]ProbeResult, len(results))
+	for i, r := range results {
+		out[i] = ProbeResult{Source: r.src, Items: len(r.items), Elapsed: r.elapsed, Err: r.err}
+	}
+	return out
+}
+
+func fetchSource(ctx context.Context, src SourceCfg, v fetch.Validators) ([]fetch.Item, fetch.Validators, error) {
+	switch src.Type {
+	case "rss":
+		return fetch.FetchRSS(ctx, fetch.RSSSource{
+			Name: src.Name, URL: src.URL, Tags: src.Tags, Weight: src.Weight, Validators: v,
+		})
+	case "html":
+		return fetch.FetchHTML(ctx, fetch.HTMLSource{
+			Name: src.Name, URL: src.URL, Tags: src.Tags, Weight: src.Weight, Selectors: src.Selectors, Validators: v,
+		})
+	}
+	return nil, v, errUnsupportedType
+}
@@ -0,0 +1,68 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/LibenHailu/cncg-bot/internal/fetch"
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+// HealthPolicy decides when a failing source is quarantined. After
+// QuarantineAfter consecutive failures the source is skipped for Backoff,
+// doubling with every further failure up to MaxBackoff. A success clears it.
+type HealthPolicy struct {
+	QuarantineAfter int // 0 disables quarantine
+	Backoff         time.Duration
+	MaxBackoff      time.Duration
+}
+
+func (h HealthPolicy) nextAttempt(failures int, now time.Time) time.Time {
+	if h.QuarantineAfter <= 0 || failures < h.QuarantineAfter {
+		return time.Time{}
+	}
+	d := h.Backoff
+	for i := h.QuarantineAfter; i < failures && (h.MaxBackoff <= 0 || d < h.MaxBackoff); i++ {
+		d *= 2
+	}
+	if h.MaxBackoff > 0 && d > h.MaxBackoff {
+		d = h.MaxBackoff
+	}
+	return now.Add(d)
+}
+
+// activeSources drops sources that are still quarantined at now.
+func (p *Pipeline) activeSources(states map[string]store.SourceState, now time.Time) []SourceCfg {
+	var out []SourceCfg
+	for _, src := range p.Sources {
+		if states[src.Name].Quarantined(now) {
+			continue
+		}
+		out = append(out, src)
+	}
+	return out
+}
+
+// recordHealth updates the source_state row for one fetch result.
+func (p *Pipeline) recordHealth(ctx context.Context, prev store.SourceState, res fetchResult, now time.Time) {
+	st := prev
+	st.Name, st.URL = res.src.Name, res.src.URL
+
+	if res.err == nil || errors.Is(res.err, fetch.ErrNotModified) {
+		st.LastSuccess = now
+		st.ConsecutiveFailures = 0
+		st.LastItemCount = len(res.items)
+		st.TotalItems += int64(len(res.items))
+		st.NextAttempt = time.Time{}
+	} else {
+		st.LastError = res.err.Error()
+		st.LastErrorAt = now
+		st.ConsecutiveFailures++
+		st.NextAttempt = p.Health.nextAttempt(st.ConsecutiveFailures, now)
+	}
+
+	if err := p.DB.SaveSourceState(ctx, st); err != nil {
+		p.logError(ctx, "db:source_state", err.Error())
+	}
+}
@@ -0,0 +1,101 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/LibenHailu/cncg-bot/internal/fetch"
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+func TestNextAttempt(t *testing.T) {
+	now := time.Date(2024, 8, 13, 9, 0, 0, 0, time.UTC)
+	h := HealthPolicy{QuarantineAfter: 3, Backoff: 6 * time.Hour, MaxBackoff: 30 * time.Hour}
+	tests := []struct {
+		failures int
+		want     time.Duration // 0: not quarantined
+	}{
+		{0, 0},
+		{1, 0},
+		{2, 0},
+		{3, 6 * time.Hour},
+		{4, 12 * time.Hour},
+		{5, 24 * time.Hour},
+		{6, 30 * time.Hour}, // 48h capped
+		{50, 30 * time.Hour},
+	}
+	for _, tt := range tests {
+		got := h.nextAttempt(tt.failures, now)
+		if tt.want == 0 && !got.IsZero() || tt.want != 0 && !got.Equal(now.Add(tt.want)) {
+			t.Errorf("nextAttempt(%d) = %v, want now+%v", tt.failures, got, tt.want)
+		}
+	}
+
+	if got := (HealthPolicy{Backoff: time.Hour}).nextAttempt(100, now); !got.IsZero() {
+		t.Errorf("QuarantineAfter 0: nextAttempt = %v, want no quarantine", got)
+	}
+	if got := (HealthPolicy{QuarantineAfter: 1, Backoff: time.Hour}).nextAttempt(5, now); !got.Equal(now.Add(16 * time.Hour)) {
+		t.Errorf("no MaxBackoff: nextAttempt(5) = %v, want now+16h", got)
+	}
+}
+
+func TestRecordHealth(t *testing.T) {
+	ctx := context.Background()
+	p := &Pipeline{
+		DB:     openTestStore(t),
+		Health: HealthPolicy{QuarantineAfter: 2, Backoff: time.Hour, MaxBackoff: 3 * time.Hour},
+	}
+	src := SourceCfg{Name: "flaky", URL: "https://example.com/feed"}
+	p.Sources = []SourceCfg{src}
+	now := time.Date(2024, 8, 13, 9, 0, 0, 0, time.UTC)
+	boom := errors.New("http 503")
+
+	record := func(res fetchResult) store.SourceState {
+		t.Helper()
+		states, err := p.DB.SourceStates(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.src = src
+		p.recordHealth(ctx, states[src.Name], res, now)
+		if states, err = p.DB.SourceStates(ctx); err != nil {
+			t.Fatal(err)
+		}
+		return states[src.Name]
+	}
+
+	st := record(fetchResult{err: boom})
+	if st.ConsecutiveFailures != 1 || st.LastError != "http 503" || st.Quarantined(now) {
+		t.Errorf("after one failure: %+v, want counted but not quarantined", st)
+	}
+	st = record(fetchResult{err: boom})
+	if st.ConsecutiveFailures != 2 || !st.NextAttempt.Equal(now.Add(time.Hour)) || !st.Quarantined(now) {
+		t.Errorf("after two failures: %+v, want quarantined for 1h", st)
+	}
+	states := map[string]store.SourceState{src.Name: st}
+	if got := p.activeSources(states, now); len(got) != 0 {
+		t.Errorf("activeSources = %v during the backoff, want none", got)
+	}
+	if got := p.activeSources(states, now.Add(time.Hour)); len(got) != 1 {
+		t.Errorf("activeSources = %v once the backoff is over, want the source back", got)
+	}
+	st = record(fetchResult{err: boom})
+	if !st.NextAttempt.Equal(now.Add(2 * time.Hour)) {
+		t.Errorf("after three failures: next attempt %v, want now+2h", st.NextAttempt)
+	}
+
+	st = record(fetchResult{err: fetch.ErrNotModified})
+	if st.ConsecutiveFailures != 0 || !st.NextAttempt.IsZero() || !st.LastSuccess.Equal(now) {
+		t.Errorf("after a 304: %+v, want healthy", st)
+	}
+	st = record(fetchResult{err: boom})
+	st = record(fetchResult{items: make([]fetch.Item, 4)})
+	if st.ConsecutiveFailures != 0 || !st.NextAttempt.IsZero() || st.LastItemCount != 4 || st.TotalItems != 4 {
+		t.Errorf("after a success: %+v, want the failures reset and 4 items counted", st)
+	}
+	if st.LastError != "http 503" {
+		t.Errorf("LastError = %q, want the last failure kept for reporting", st.LastError)
+	}
+}
@@ -0,0 +1,61 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestOGImagesConcurrent fetches the article pages of four new items that
+// each take 400ms to answer: with four workers the run takes about one
+// page's time, not four.
+func TestOGImagesConcurrent(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/feed" {
+			fmt.Fprint(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>t</title>`)
+			for i := 0; i < 4; i++ {
+				fmt.Fprintf(w, `<item><title>Post %d</title><link>%s/post/%d</link><pubDate>%s</pubDate></item>`,
+					i, srv.URL, i, time.Now().UTC().Format(time.RFC1123Z))
+			}
+			fmt.Fprint(w, `</channel></rss>`)
+			return
+		}
+		time.Sleep(400 * time.Millisecond)
+		fmt.Fprintf(w, `<html><head><meta property="og:image" content="/img%s.png"></head></html>`, r.URL.Path)
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	p := &Pipeline{
+		DB:          openTestStore(t),
+		Filters:     Filters{MaxAgeDays: 21},
+		Sources:     []SourceCfg{{Name: "test", Type: "rss", URL: srv.URL + "/feed", Weight: 1}},
+		Concurrency: 4,
+		OGImage:     true,
+	}
+	start := time.Now()
+	if err := p.RunOnce(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed > 1200*time.Millisecond {
+		t.Errorf("RunOnce took %v, want the pages fetched side by side", elapsed)
+	}
+
+	items, err := p.DB.NextUnposted(ctx, []string{"telegram"}, 0, time.Now().AddDate(0, 0, -1), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 4 {
+		t.Fatalf("%d items stored, want 4", len(items))
+	}
+	for _, it := range items {
+		if want := srv.URL + "/img" + strings.TrimPrefix(it.URL, srv.URL) + ".png"; it.Image != want {
+			t.Errorf("%s: image %q, want %q", it.Title, it.Image, want)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestDryRunPendingConcurrent runs the daemon's fetch and post jobs side by
+// side on one dry-run pipeline; go test -race flags unguarded access to
+// Pending.
+func TestDryRunPendingConcurrent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `<?xml version="1.0"?><rss version="2.0"><channel><title>t</title>
+<item><title>Kubernetes news</title><link>https://example.com/k8s</link><pubDate>%s</pubDate></item>
+</channel></rss>`, time.Now().UTC().Format(time.RFC1123Z))
+	}))
+	defer srv.Close()
+
+	ctx := context.Background()
+	p := &Pipeline{
+		DB:      openTestStore(t),
+		Filters: Filters{MaxAgeDays: 21},
+		Sources: []SourceCfg{{Name: "test", Type: "rss", URL: srv.URL, Weight: 1}},
+		DryRun:  true,
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := p.RunOnce(ctx); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if _, err := p.NextBatch(ctx, []string{"telegram"}, 10); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	items, err := p.NextBatch(ctx, []string{"telegram"}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Title != "Kubernetes news" {
+		t.Errorf("NextBatch = %+v, want the pending item", items)
+	}
+}
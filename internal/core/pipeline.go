@@ -2,7 +2,10 @@ package core
 
 import (
 	"context"
+	"errors"
+	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/LibenHailu/cncg-bot/internal/fetch"
@@ -13,92 +16,248 @@ import (
 type Filters struct {
 	MaxAgeDays int
 	MinScore   float64
-	Positive   []string
-	Negative   []string
+	Positive   []Keyword
+	Negative   []Keyword
 }
 
 type SourceCfg struct {
-	Name   string
-	Type   string
-	URL    string
-	Weight float64
-	Tags   []string
+	Name      string
+	Type      string
+	URL       string
+	Weight    float64
+	Tags      []string
+	Selectors fetch.Selectors // "html" only
 }
 
 type Pipeline struct {
 	Filters Filters
 	Sources []SourceCfg
 	DB      *store.Store
+
+	Concurrency   int           // parallel fetches; 0 means defaultConcurrency
+	SourceTimeout time.Duration // per-source fetch timeout; 0 means defaultSourceTimeout
+	Health        HealthPolicy
+	Scoring       *Scoring // nil means DefaultScoring
+	Dedup         *Dedup   // nil disables near-duplicate detection
+	// OGImage fetches the article page of new items scoring at least
+	// MinScore that have no image, to take its og:image. The pages are
+	// fetched in parallel after the sources, see ogImages.
+	OGImage bool
+
+	// DryRun makes RunOnce write nothing to the store: new items are kept
+	// in Pending instead of inserted, and health, feed cache and errors are
+	// not recorded. NextBatch merges Pending into its selection.
+	DryRun  bool
+	Pending []store.Item
+
+	// mu guards Pending, which a daemon's post job reads while the fetch
+	// job runs. RunOnce replaces it whole once the run is complete.
+	mu sync.Mutex
+}
+
+// Dedup links items whose SimHash of title+summary is within MaxDistance
+// bits of an item published in the preceding Window.
+type Dedup struct {
+	MaxDistance int
+	Window      time.Duration
 }
 
 func (p *Pipeline) RunOnce(ctx context.Context) error {
 	now := time.Now().UTC()
 	cutoff := now.AddDate(0, 0, -p.Filters.MaxAgeDays)
+	var pending []store.Item
+
+	caches, err := p.DB.FeedCaches(ctx)
+	if err != nil {
+		// Not fatal: without validators every source is fetched in full.
+		p.logError(ctx, "db:feed_cache", err.Error())
+	}
+
+	states, err := p.DB.SourceStates(ctx)
+	if err != nil {
+		p.logError(ctx, "db:source_state", err.Error())
+	}
+
+	// Build every source's records first so the og:image lookups can run
+	// side by side instead of one page at a time.
+	var batches []sourceBatch
+	for _, res := range p.fetchAll(ctx, p.activeSources(states, now), caches) {
+		src, items, err := res.src, res.items, res.err
+		if !p.DryRun {
+			p.recordHealth(ctx, states[src.Name], res, now)
+		}
+		if errors.Is(err, fetch.ErrNotModified) {
+			continue
+		}
+		if errors.Is(err, errUnsupportedType) {
+			p.logError(ctx, "fetch", "unsupported source type: "+src.Type)
+			continue
+		}
+		if err != nil {
+			p.logError(ctx, "fetch:"+src.Type, src.Name+" : "+err.Error())
+			continue
+		}
+		b := sourceBatch{res: res}
+		for _, it := range items {
+			if it.PublishedAt.Before(cutoff) {
+				continue
+			}
+			url := util.CanonURL(it.URL)
+			title := strings.TrimSpace(it.Title)
+			if title == "" || url == "" {
+				continue
+			}
 
-	for _, src := range p.Sources {
-		switch src.Type {
-		case "rss":
-			items, err := fetch.FetchRSS(ctx, fetch.RSSSource{
-				Name: src.Name, URL: src.URL, Tags: src.Tags, Weight: src.Weight,
+			rawSum := Summarize(it.Summary, 3)
+			bd := p.scoreItem(title, rawSum, src.Weight, now.Sub(it.PublishedAt))
+			b.recs = append(b.recs, store.Item{
+				Source: src.Name, Title: title, URL: url,
+				Summary:     rawSum,
+				PublishedAt: it.PublishedAt,
+				Tags:        strings.Join(src.Tags, ","),
+				Hash:        store.Hash(url, title),
+				Score:       bd.Total,
+				ScoreDetail: bd.JSON(),
+				SimHash:     util.SimHash(title, rawSum),
+				Image:       it.Image,
 			})
+		}
+		batches = append(batches, b)
+	}
+	if p.OGImage {
+		p.ogImages(ctx, batches)
+	}
+
+	for _, b := range batches {
+		stored := true
+		for _, rec := range b.recs {
+			if p.DryRun {
+				pending = p.addPending(ctx, pending, rec)
+				continue
+			}
+			inserted, err := p.DB.InsertIfNew(ctx, rec)
 			if err != nil {
-				p.DB.LogError(ctx, "fetch:rss", src.Name+" : "+err.Error())
+				p.logError(ctx, "db:insert", err.Error())
+				stored = false
 				continue
 			}
-
-			for _, it := range items {
-				if it.PublishedAt.Before(cutoff) {
-					continue
-				}
-				url := util.CanonURL(it.URL)
-				title := strings.TrimSpace(it.Title)
-				if title == "" || url == "" {
-					continue
+			if inserted && p.Dedup != nil {
+				since := rec.PublishedAt.Add(-p.Dedup.Window)
+				if _, err := p.DB.LinkNearDuplicate(ctx, rec.Hash, p.Dedup.MaxDistance, since); err != nil {
+					p.logError(ctx, "db:dedup", err.Error())
 				}
+			}
+		}
 
-				rawSum := Summarize(it.Summary, 3)
-				score := p.scoreItem(title+" "+rawSum, src.Weight)
-				rec := store.Item{
-					Source: src.Name, Title: title, URL: url,
-					Summary:     rawSum,
-					PublishedAt: it.PublishedAt,
-					Tags:        strings.Join(src.Tags, ","),
-					Hash:        store.Hash(url, title),
-					Score:       score,
-				}
-				if _, err := p.DB.InsertIfNew(ctx, rec); err != nil {
-					p.DB.LogError(ctx, "db:insert", err.Error())
-				}
+		// Remember validators only once the items are stored, so a failed
+		// run is not followed by a 304 that hides them.
+		res := b.res
+		fc := store.FeedCache{ETag: res.validators.ETag, LastModified: res.validators.LastModified}
+		if fc != caches[res.src.URL] && stored && !p.DryRun {
+			if err := p.DB.SaveFeedCache(ctx, res.src.URL, fc); err != nil {
+				p.logError(ctx, "db:feed_cache", err.Error())
 			}
-		default:
-			p.DB.LogError(ctx, "fetch", "unsupported source type: "+src.Type)
 		}
 	}
+
+	p.mu.Lock()
+	p.Pending = pending
+	p.mu.Unlock()
 	return nil
 }
 
-func (p *Pipeline) scoreItem(text string, sourceWeight float64) float64 {
-	t := strings.ToLower(text)
-	score := 0.2 * sourceWeight
-	for _, kw := range p.Filters.Positive {
-		if strings.Contains(t, strings.ToLower(kw)) {
-			score += 0.1
+// sourceBatch is a fetched source with the records built from its items.
+type sourceBatch struct {
+	res  fetchResult
+	recs []store.Item
+}
+
+// ogImages fills in the og:image of new records scoring at least MinScore
+// that have none. Pages are fetched like sources, Concurrency at a time and
+// each within SourceTimeout, and the lookups as a whole stop after
+// ogImageBudget: records left over are stored without an image. Records
+// already in the store are skipped, their image was settled on insert.
+func (p *Pipeline) ogImages(ctx context.Context, batches []sourceBatch) {
+	var todo []*store.Item
+	for i := range batches {
+		for j := range batches[i].recs {
+			rec := &batches[i].recs[j]
+			if rec.Image != "" || rec.Score < p.Filters.MinScore {
+				continue
+			}
+			if exists, err := p.DB.HasItem(ctx, rec.Hash); err != nil || exists {
+				continue
+			}
+			todo = append(todo, rec)
 		}
 	}
-	for _, kw := range p.Filters.Negative {
-		if strings.Contains(t, strings.ToLower(kw)) {
-			score -= 0.2
+	if len(todo) == 0 {
+		return
+	}
+
+	workers := p.Concurrency
+	if workers <= 0 {
+		workers = defaultConcurrency
+	}
+	timeout := p.SourceTimeout
+	if timeout <= 0 {
+		timeout = defaultSourceTimeout
+	}
+	bctx, cancel := context.WithTimeout(ctx, ogImageBudget)
+	defer cancel()
+
+	errs := make([]error, len(todo))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				ictx, cancel := context.WithTimeout(bctx, timeout)
+				todo[i].Image, errs[i] = fetch.OGImage(ictx, todo[i].URL)
+				cancel()
+			}
+		}()
+	}
+feed:
+	for i := range todo {
+		select {
+		case jobs <- i:
+		case <-bctx.Done():
+			break feed
 		}
 	}
-	if score < 0 {
-		score = 0
+	close(jobs)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			p.logError(ctx, "fetch:og_image", todo[i].URL+" : "+err.Error())
+		}
 	}
-	if score > 1 {
-		score = 1
+	if bctx.Err() != nil && ctx.Err() == nil {
+		p.logError(ctx, "fetch:og_image", "lookups stopped after "+ogImageBudget.String())
 	}
-	if score >= 0 {
-		return 1
+}
+
+// logError records a pipeline error in the store, or only in the process
+// log during a dry run.
+func (p *Pipeline) logError(ctx context.Context, component, msg string) {
+	if p.DryRun {
+		log.Printf("%s: %s", component, msg)
+		return
 	}
+	p.DB.LogError(ctx, component, msg)
+}
 
-	return score
+func (p *Pipeline) scoreItem(title, body string, sourceWeight float64, age time.Duration) Breakdown {
+	return p.scoring().Score(p.Filters.Positive, p.Filters.Negative, title, body, sourceWeight, age)
+}
+
+func (p *Pipeline) scoring() Scoring {
+	if p.Scoring != nil {
+		return *p.Scoring
+	}
+	return DefaultScoring
 }
@@ -0,0 +1,174 @@
+package core
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Keyword is a scoring term. A zero Weight means the Scoring default for
+// its list (KeywordWeight for positives, NegativeWeight for negatives).
+type Keyword struct {
+	Term   string
+	Weight float64
+}
+
+// Scoring holds the knobs of the scoring engine:
+//
+//	score = clamp((SourceWeight*w + Σ keyword points) * decay − Σ penalties, 0, 1)
+//
+// where a keyword hit in the title is worth TitleBoost times a body hit and
+// decay halves every HalfLife of item age.
+type Scoring struct {
+	SourceWeight   float64
+	KeywordWeight  float64
+	NegativeWeight float64
+	TitleBoost     float64
+	HalfLife       time.Duration // 0 disables recency decay
+}
+
+// DefaultScoring is used by pipelines that do not set Pipeline.Scoring.
+var DefaultScoring = Scoring{
+	SourceWeight:   0.4,
+	KeywordWeight:  0.15,
+	NegativeWeight: 0.3,
+	TitleBoost:     2,
+	HalfLife:       7 * 24 * time.Hour,
+}
+
+// Hit is one keyword match and the points it contributed (negative for
+// penalties).
+type Hit struct {
+	Term   string  `json:"term"`
+	Where  string  `json:"where"` // "title" or "body"
+	Points float64 `json:"points"`
+}
+
+// Breakdown records how an item's score was reached so the weights can be
+// tuned. It is stored as JSON next to the item.
+type Breakdown struct {
+	Source    float64 `json:"source"`
+	Keywords  []Hit   `json:"keywords,omitempty"`
+	Decay     float64 `json:"decay"`
+	Penalties []Hit   `json:"penalties,omitempty"`
+	Total     float64 `json:"total"`
+}
+
+func (b Breakdown) JSON() string {
+	out, _ := json.Marshal(b)
+	return string(out)
+}
+
+// Score rates an item from its title, body text, source weight and age.
+func (sc Scoring) Score(positive, negative []Keyword, title, body string, sourceWeight float64, age time.Duration) Breakdown {
+	title, body = strings.ToLower(title), strings.ToLower(body)
+
+	b := Breakdown{Source: sc.SourceWeight * sourceWeight, Decay: 1}
+	points := b.Source
+	for _, kw := range positive {
+		if h, ok := sc.hit(kw, sc.KeywordWeight, title, body); ok {
+			b.Keywords = append(b.Keywords, h)
+			points += h.Points
+		}
+	}
+
+	b.Decay = sc.decay(age)
+	points *= b.Decay
+
+	for _, kw := range negative {
+		if h, ok := sc.hit(kw, sc.NegativeWeight, title, body); ok {
+			h.Points = -h.Points
+			b.Penalties = append(b.Penalties, h)
+			points += h.Points
+		}
+	}
+
+	b.Total = math.Max(0, math.Min(1, points))
+	return b
+}
+
+// Rescore recomputes the decay and total of b for an item now age old.
+// Scores are stored when an item is fetched, usually within hours of
+// publication, so selection rescores them to let older items sink.
+func (sc Scoring) Rescore(b Breakdown, age time.Duration) Breakdown {
+	points := b.Source
+	for _, h := range b.Keywords {
+		points += h.Points
+	}
+	b.Decay = sc.decay(age)
+	points *= b.Decay
+	for _, h := range b.Penalties {
+		points += h.Points
+	}
+	b.Total = math.Max(0, math.Min(1, points))
+	return b
+}
+
+func (sc Scoring) decay(age time.Duration) float64 {
+	if sc.HalfLife > 0 && age > 0 {
+		return math.Pow(0.5, float64(age)/float64(sc.HalfLife))
+	}
+	return 1
+}
+
+// hit reports whether kw occurs in title or body and how many points it
+// earns; a title match takes precedence.
+func (sc Scoring) hit(kw Keyword, def float64, title, body string) (Hit, bool) {
+	w := kw.Weight
+	if w == 0 {
+		w = def
+	}
+	term := strings.ToLower(strings.TrimSpace(kw.Term))
+	switch {
+	case term == "":
+		return Hit{}, false
+	case containsWord(title, term):
+		boost := sc.TitleBoost
+		if boost <= 0 {
+			boost = 1
+		}
+		return Hit{Term: kw.Term, Where: "title", Points: w * boost}, true
+	case containsWord(body, term):
+		return Hit{Term: kw.Term, Where: "body", Points: w}, true
+	}
+	return Hit{}, false
+}
+
+// containsWord reports whether term occurs in s on word boundaries, so
+// "otel" does not match "hotel" and "argo" does not match "cargo".
+func containsWord(s, term string) bool {
+	for i := 0; ; {
+		j := strings.Index(s[i:], term)
+		if j < 0 {
+			return false
+		}
+		start, end := i+j, i+j+len(term)
+		if !wordRuneBefore(s, start) && !wordRuneAt(s, end) {
+			return true
+		}
+		i = start + 1
+	}
+}
+
+func wordRuneBefore(s string, i int) bool {
+	if i == 0 {
+		return false
+	}
+	r, _ := utf8.DecodeLastRuneInString(s[:i])
+	return isWordRune(r)
+}
+
+func wordRuneAt(s string, i int) bool {
+	if i >= len(s) {
+		return false
+	}
+	r, _ := utf8.DecodeRuneInString(s[i:])
+	return isWordRune(r)
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,89 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+// NextBatch selects up to limit new items to post to destinations: the
+// unposted items in the store plus, during a dry run, the Pending items of
+// the last RunOnce. Items older than MaxAgeDays are left out and the rest
+// are rescored for their current age, so the ranking reflects recency at
+// posting time rather than at fetch time. After them come up to limit
+// items to retry at destinations where they failed; these only go to
+// those destinations.
+func (p *Pipeline) NextBatch(ctx context.Context, destinations []string, limit int) ([]store.Item, error) {
+	now := time.Now().UTC()
+	since := now.AddDate(0, 0, -p.Filters.MaxAgeDays)
+	// Decay only lowers scores, so the stored score is a safe prefilter.
+	stored, err := p.DB.NextUnposted(ctx, destinations, p.Filters.MinScore, since, 0)
+	if err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	pending := p.Pending
+	p.mu.Unlock()
+	for _, it := range pending {
+		if !it.PublishedAt.Before(since) {
+			stored = append(stored, it)
+		}
+	}
+
+	items := p.rescore(stored, now)
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].Score != items[j].Score {
+			return items[i].Score > items[j].Score
+		}
+		return items[i].PublishedAt.After(items[j].PublishedAt)
+	})
+	if len(items) > limit {
+		items = items[:limit]
+	}
+
+	retries, err := p.DB.NextRetries(ctx, destinations, p.Filters.MinScore, since, limit)
+	if err != nil {
+		return nil, err
+	}
+	return append(items, p.rescore(retries, now)...), nil
+}
+
+// rescore updates the scores of items for their age at now and drops those
+// that fell below MinScore.
+func (p *Pipeline) rescore(items []store.Item, now time.Time) []store.Item {
+	sc := p.scoring()
+	var out []store.Item
+	for _, it := range items {
+		var bd Breakdown
+		if err := json.Unmarshal([]byte(it.ScoreDetail), &bd); err == nil {
+			bd = sc.Rescore(bd, now.Sub(it.PublishedAt))
+			it.Score, it.ScoreDetail = bd.Total, bd.JSON()
+		}
+		if it.Score >= p.Filters.MinScore {
+			out = append(out, it)
+		}
+	}
+	return out
+}
+
+// addPending adds rec to pending for a dry run unless the store already
+// has it.
+func (p *Pipeline) addPending(ctx context.Context, pending []store.Item, rec store.Item) []store.Item {
+	exists, err := p.DB.HasItem(ctx, rec.Hash)
+	if err != nil {
+		p.logError(ctx, "db:select", err.Error())
+		return pending
+	}
+	if exists {
+		return pending
+	}
+	for _, it := range pending {
+		if it.Hash == rec.Hash {
+			return pending
+		}
+	}
+	return append(pending, rec)
+}
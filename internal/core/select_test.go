@@ -0,0 +1,68 @@
+package core
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+func openTestStore(t *testing.T) *store.Store {
+	t.Helper()
+	db, err := store.Open("file:" + filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// TestNextBatchRescores checks that items are ranked by their score at
+// selection time: a backlog item scored when it was fresh must not outrank
+// today's news, and items past MaxAgeDays are not selected at all.
+func TestNextBatchRescores(t *testing.T) {
+	ctx := context.Background()
+	db := openTestStore(t)
+	now := time.Now().UTC()
+	p := &Pipeline{DB: db, Filters: Filters{MaxAgeDays: 21, MinScore: 0.2}}
+
+	add := func(title string, weight float64, published time.Time) {
+		// Scored as the hourly fetch would have: an hour after publication.
+		bd := p.scoreItem(title, "", weight, time.Hour)
+		if _, err := db.InsertIfNew(ctx, store.Item{
+			Source: "test", Title: title, URL: "https://example.com/" + title, PublishedAt: published,
+			Hash: store.Hash(title, title), Score: bd.Total, ScoreDetail: bd.JSON(),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	add("backlog", 1, now.Add(-14*24*time.Hour)) // 0.4 when fetched, 0.1 now
+	add("today", 0.8, now.Add(-2*time.Hour))     // 0.32
+	add("stale", 1, now.Add(-30*24*time.Hour))   // past max_age_days
+
+	items, err := p.NextBatch(ctx, []string{"telegram"}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Title != "today" {
+		var titles []string
+		for _, it := range items {
+			titles = append(titles, it.Title)
+		}
+		t.Fatalf("NextBatch = %v, want [today]", titles)
+	}
+	if items[0].Score >= 0.32 {
+		t.Errorf("today scored %g, want it decayed below its stored 0.32", items[0].Score)
+	}
+
+	p.Filters.MinScore = 0
+	items, err = p.NextBatch(ctx, []string{"telegram"}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items[0].Title != "today" || items[1].Title != "backlog" {
+		t.Fatalf("NextBatch with min_score 0 = %+v, want today then backlog", items)
+	}
+}
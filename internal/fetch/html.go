@@ -1 +1,150 @@
-package fetch
\ No newline at end of file
+package fetch
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+// Selectors are the CSS selectors used to scrape a blog list page. Title,
+// Link, Date and Summary are evaluated relative to each Item match.
+type Selectors struct {
+	Item    string
+	Title   string
+	Link    string // element with href, or containing one; defaults to Title
+	Date    string // reads the datetime attribute if present, else the text
+	Summary string
+	Image   string // img element (src) or element with a src/content attribute
+}
+
+type HTMLSource struct {
+	Name       string
+	URL        string
+	Tags       []string
+	Weight     float64
+	Selectors  Selectors
+	Validators Validators // from the previous fetch, for conditional GET
+}
+
+// dateLayouts are tried in order when parsing scraped dates.
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"January 2, 2006",
+	"Jan 2, 2006",
+	"2 January 2006",
+	"2 Jan 2006",
+	"January 2006",
+	time.RFC1123Z,
+	time.RFC1123,
+}
+
+// FetchHTML scrapes src.URL. It returns ErrNotModified when the page is
+// unchanged since src.Validators were recorded.
+func FetchHTML(ctx context.Context, src HTMLSource) ([]Item, Validators, error) {
+	body, v, err := get(ctx, src.URL, src.Validators)
+	if err != nil {
+		return nil, v, err
+	}
+	defer body.Close()
+
+	doc, err := goquery.NewDocumentFromReader(body)
+	if err != nil {
+		return nil, v, err
+	}
+	base, err := url.Parse(src.URL)
+	if err != nil {
+		return nil, v, err
+	}
+	return parseHTML(doc, base, src), v, nil
+}
+
+func parseHTML(doc *goquery.Document, base *url.URL, src HTMLSource) []Item {
+	sel := src.Selectors
+	linkSel := firstNonEmpty(sel.Link, sel.Title)
+
+	var items []Item
+	doc.Find(sel.Item).Each(func(_ int, s *goquery.Selection) {
+		t := collapseSpace(s.Find(sel.Title).First().Text())
+		u := resolveURL(base, findHref(s.Find(linkSel).First()))
+		if t == "" || u == "" {
+			return
+		}
+		pub := time.Now().UTC()
+		if sel.Date != "" {
+			if d, ok := parseDate(s.Find(sel.Date).First()); ok {
+				pub = d
+			}
+		}
+		var summary string
+		if sel.Summary != "" {
+			summary = collapseSpace(s.Find(sel.Summary).First().Text())
+		}
+		var image string
+		if sel.Image != "" {
+			image = resolveURL(base, findSrc(s.Find(sel.Image).First()))
+		}
+		items = append(items, Item{
+			Source: src.Name, Title: t, URL: u, PublishedAt: pub, Summary: summary, Tags: src.Tags, Image: image,
+		})
+	})
+	return items
+}
+
+// findHref returns the href of s itself or of the first link inside it.
+func findHref(s *goquery.Selection) string {
+	if href, ok := s.Attr("href"); ok {
+		return href
+	}
+	href, _ := s.Find("a[href]").First().Attr("href")
+	return href
+}
+
+// findSrc returns the image URL of s: its src, a lazy-loading data-src, or
+// a content attribute, or those of the first img inside it.
+func findSrc(s *goquery.Selection) string {
+	if !s.Is("img") {
+		if inner := s.Find("img").First(); inner.Length() > 0 {
+			s = inner
+		}
+	}
+	for _, attr := range []string{"src", "data-src", "content"} {
+		if v, ok := s.Attr(attr); ok && strings.TrimSpace(v) != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+func resolveURL(base *url.URL, href string) string {
+	href = strings.TrimSpace(href)
+	if href == "" {
+		return ""
+	}
+	ref, err := url.Parse(href)
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(ref).String()
+}
+
+func parseDate(s *goquery.Selection) (time.Time, bool) {
+	raw := collapseSpace(s.Text())
+	if v, ok := s.Attr("datetime"); ok && strings.TrimSpace(v) != "" {
+		raw = strings.TrimSpace(v)
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
+
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
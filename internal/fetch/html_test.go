@@ -0,0 +1,125 @@
+package fetch
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PuerkitoBio/goquery"
+)
+
+const blogPage = `<html><body>
+<article class="post">
+  <h2><a href="first-post/">  First
+      post </a></h2>
+  <time datetime="2024-08-13T10:00:00Z">Aug 13</time>
+  <p class="excerpt">The   first one.</p>
+  <img class="thumb" data-src="/img/first.png">
+</article>
+<article class="post">
+  <h2>Second post</h2>
+  <a class="more" href="/news/second">Read more</a>
+  <span class="date">July 4, 2024</span>
+</article>
+<article class="post">
+  <h2>No link here</h2>
+</article>
+<article class="post">
+  <h2><a href="https://other.example/third">Third post</a></h2>
+  <span class="date">not a date</span>
+</article>
+<div class="sidebar"><h2><a href="/ignored">Not an item</a></h2></div>
+</body></html>`
+
+func TestFetchHTML(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, blogPage)
+	}))
+	defer srv.Close()
+
+	before := time.Now().UTC()
+	items, _, err := FetchHTML(context.Background(), HTMLSource{
+		Name: "Blog", URL: srv.URL + "/blog/", Tags: []string{"t"},
+		Selectors: Selectors{
+			Item: "article.post", Title: "h2", Link: "h2 a, a.more",
+			Date: "time, .date", Summary: ".excerpt", Image: ".thumb",
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type row struct{ title, url, date, summary, image string }
+	var got []row
+	for _, it := range items {
+		date := it.PublishedAt.Format(time.RFC3339)
+		if !it.PublishedAt.Before(before) {
+			date = "now" // no date, or one that did not parse
+		}
+		got = append(got, row{it.Title, it.URL, date, it.Summary, it.Image})
+	}
+	want := []row{
+		{"First post", srv.URL + "/blog/first-post/", "2024-08-13T10:00:00Z", "The first one.", srv.URL + "/img/first.png"},
+		{"Second post", srv.URL + "/news/second", "2024-07-04T00:00:00Z", "", ""},
+		{"Third post", "https://other.example/third", "now", "", ""},
+	}
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("items:\n got %v\nwant %v", got, want)
+	}
+
+	// Without a Link selector the link is looked up inside the title.
+	items, _, err = FetchHTML(context.Background(), HTMLSource{
+		URL: srv.URL + "/blog/", Selectors: Selectors{Item: "article.post", Title: "h2"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var titles []string
+	for _, it := range items {
+		titles = append(titles, it.Title)
+	}
+	if strings.Join(titles, "|") != "First post|Third post" {
+		t.Errorf("titles without a link selector = %v, want the two with a link in the title", titles)
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	want := time.Date(2024, 8, 13, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		html string
+		want time.Time
+	}{
+		{`<time datetime="2024-08-13T00:00:00Z">yesterday</time>`, want},
+		{`<time datetime="2024-08-13T02:00:00+02:00"></time>`, want},
+		{`<time datetime="2024-08-13">13 Aug</time>`, want},
+		{`<span>2024-08-13T00:00:00</span>`, want},
+		{`<span>2024-08-13</span>`, want},
+		{`<span> August 13, 2024 </span>`, want},
+		{`<span>Aug 13, 2024</span>`, want},
+		{`<span>13 August 2024</span>`, want},
+		{`<span>13 Aug 2024</span>`, want},
+		{`<span>August 2024</span>`, time.Date(2024, 8, 1, 0, 0, 0, 0, time.UTC)},
+		{`<span>Tue, 13 Aug 2024 00:00:00 +0000</span>`, want},
+		{`<span>Tue, 13 Aug 2024 00:00:00 UTC</span>`, want},
+		{`<time datetime=" ">Aug 13, 2024</time>`, want}, // blank attribute: the text counts
+	}
+	for _, tt := range tests {
+		doc, err := goquery.NewDocumentFromReader(strings.NewReader(tt.html))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, ok := parseDate(doc.Find("time, span").First())
+		if !ok || !got.Equal(tt.want) {
+			t.Errorf("parseDate(%s) = %v, %v; want %v", tt.html, got, ok, tt.want)
+		}
+	}
+
+	doc, _ := goquery.NewDocumentFromReader(strings.NewReader(`<span>soon</span>`))
+	if _, ok := parseDate(doc.Find("span")); ok {
+		t.Error("parseDate(soon) succeeded")
+	}
+}
@@ -0,0 +1,55 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+const userAgent = "cncg-bot (+https://github.com/LibenHailu/cncg-bot)"
+
+// ErrNotModified is returned when the server answers a conditional GET
+// with 304: the source has nothing new since the last fetch.
+var ErrNotModified = errors.New("not modified")
+
+// Validators are the HTTP cache validators remembered between runs and sent
+// back as If-None-Match / If-Modified-Since.
+type Validators struct {
+	ETag         string
+	LastModified string
+}
+
+// get performs a conditional GET and returns the body along with the
+// validators of the response. The caller must close the body.
+func get(ctx context.Context, url string, v Validators) (io.ReadCloser, Validators, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, v, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	if v.ETag != "" {
+		req.Header.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		req.Header.Set("If-Modified-Since", v.LastModified)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, v, err
+	}
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return resp.Body, Validators{
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+		}, nil
+	case http.StatusNotModified:
+		resp.Body.Close()
+		return nil, v, ErrNotModified
+	}
+	resp.Body.Close()
+	return nil, v, fmt.Errorf("http %d", resp.StatusCode)
+}
@@ -0,0 +1,55 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConditionalGet(t *testing.T) {
+	const etag, lastMod = `"v1"`, "Tue, 13 Aug 2024 10:00:00 GMT"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == etag && r.Header.Get("If-Modified-Since") == lastMod {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", lastMod)
+		w.Write([]byte(`<?xml version="1.0"?><rss version="2.0"><channel><title>t</title>
+<item><title>Post</title><link>https://example.com/post</link></item></channel></rss>`))
+	}))
+	defer srv.Close()
+	ctx := context.Background()
+
+	items, v, err := FetchRSS(ctx, RSSSource{Name: "t", URL: srv.URL})
+	if err != nil || len(items) != 1 {
+		t.Fatalf("first fetch = %d items, %v", len(items), err)
+	}
+	if v != (Validators{ETag: etag, LastModified: lastMod}) {
+		t.Errorf("validators = %+v", v)
+	}
+
+	items, v2, err := FetchRSS(ctx, RSSSource{Name: "t", URL: srv.URL, Validators: v})
+	if !errors.Is(err, ErrNotModified) || items != nil {
+		t.Errorf("second fetch = %d items, %v; want ErrNotModified", len(items), err)
+	}
+	if v2 != v {
+		t.Errorf("validators after 304 = %+v, want the ones sent", v2)
+	}
+
+	if _, _, err := FetchRSS(ctx, RSSSource{Name: "t", URL: srv.URL, Validators: Validators{ETag: `"old"`}}); err != nil {
+		t.Errorf("fetch with stale validators = %v, want the full feed", err)
+	}
+}
+
+func TestGetStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "gone", http.StatusGone)
+	}))
+	defer srv.Close()
+	if _, _, err := get(context.Background(), srv.URL, Validators{}); err == nil || err.Error() != "http 410" {
+		t.Errorf("get = %v, want http 410", err)
+	}
+}
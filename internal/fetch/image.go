@@ -0,0 +1,93 @@
+package fetch
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/mmcdole/gofeed"
+	ext "github.com/mmcdole/gofeed/extensions"
+)
+
+// itemImage picks a preview image for a feed entry: the item image, an
+// image enclosure, or a Media RSS content/thumbnail, in that order.
+func itemImage(e *gofeed.Item) string {
+	if e.Image != nil && e.Image.URL != "" {
+		return e.Image.URL
+	}
+	for _, enc := range e.Enclosures {
+		if enc != nil && enc.URL != "" && strings.HasPrefix(enc.Type, "image/") {
+			return enc.URL
+		}
+	}
+	media := e.Extensions["media"]
+	if media == nil {
+		return ""
+	}
+	if u := mediaImage(media["content"]); u != "" {
+		return u
+	}
+	if u := mediaImage(media["thumbnail"]); u != "" {
+		return u
+	}
+	for _, g := range media["group"] {
+		if u := mediaImage(g.Children["content"]); u != "" {
+			return u
+		}
+		if u := mediaImage(g.Children["thumbnail"]); u != "" {
+			return u
+		}
+	}
+	return ""
+}
+
+// mediaImage returns the first media:content or media:thumbnail that is an
+// image. Thumbnails carry no type, so an untyped element counts.
+func mediaImage(es []ext.Extension) string {
+	for _, e := range es {
+		u := e.Attrs["url"]
+		if u == "" {
+			continue
+		}
+		medium, typ := e.Attrs["medium"], e.Attrs["type"]
+		if medium == "image" || strings.HasPrefix(typ, "image/") || (medium == "" && typ == "") {
+			return u
+		}
+	}
+	return ""
+}
+
+// OGImage fetches the article at pageURL and returns its og:image (or
+// twitter:image), resolved against the page URL. It returns "" without an
+// error when the page declares none.
+func OGImage(ctx context.Context, pageURL string) (string, error) {
+	body, _, err := get(ctx, pageURL, Validators{})
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	doc, err := goquery.NewDocumentFromReader(body)
+	if err != nil {
+		return "", err
+	}
+	base, err := url.Parse(pageURL)
+	if err != nil {
+		return "", err
+	}
+	for _, sel := range []string{
+		`meta[property="og:image:secure_url"]`,
+		`meta[property="og:image"]`,
+		`meta[name="og:image"]`,
+		`meta[name="twitter:image"]`,
+		`meta[property="twitter:image"]`,
+	} {
+		if c, ok := doc.Find(sel).First().Attr("content"); ok {
+			if u := resolveURL(base, c); u != "" {
+				return u, nil
+			}
+		}
+	}
+	return "", nil
+}
@@ -9,10 +9,11 @@ import (
 )
 
 type RSSSource struct {
-	Name   string
-	URL    string
-	Tags   []string
-	Weight float64
+	Name       string
+	URL        string
+	Tags       []string
+	Weight     float64
+	Validators Validators // from the previous fetch, for conditional GET
 }
 
 type Item struct {
@@ -22,12 +23,22 @@ type Item struct {
 	PublishedAt time.Time
 	Summary     string
 	Tags        []string
+	Image       string // preview image URL, if the source has one
 }
 
-func FetchRSS(ctx context.Context, src RSSSource) ([]Item, error) {
+// FetchRSS downloads and parses the feed at src.URL. It returns
+// ErrNotModified when the feed is unchanged since src.Validators were
+// recorded.
+func FetchRSS(ctx context.Context, src RSSSource) ([]Item, Validators, error) {
+	body, v, err := get(ctx, src.URL, src.Validators)
+	if err != nil {
+		return nil, v, err
+	}
+	defer body.Close()
+
 	fp := gofeed.NewParser()
-	feed, err := fp.ParseURLWithContext(src.URL, ctx)
-	if err != nil { return nil, err }
+	feed, err := fp.Parse(body)
+	if err != nil { return nil, v, err }
 
 	var items []Item
 	for _, e := range feed.Items {
@@ -39,9 +50,10 @@ func FetchRSS(ctx context.Context, src RSSSource) ([]Item, error) {
 		summary := strings.TrimSpace(firstNonEmpty(e.Description, e.Content))
 		items = append(items, Item{
 			Source: src.Name, Title: t, URL: u, PublishedAt: pub, Summary: summary, Tags: src.Tags,
+			Image: itemImage(e),
 		})
 	}
-	return items, nil
+	return items, v, nil
 }
 
 func firstNonEmpty(ss ...string) string {
@@ -0,0 +1,178 @@
+package poster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+// Discord embed limits.
+const (
+	discordTitleMax       = 256
+	discordDescriptionMax = 4096
+	discordFooterMax      = 2048
+)
+
+// discordAttempts bounds how often a rate-limited message is retried.
+const discordAttempts = 3
+
+// discordColors is the palette tags are hashed into, so a tag keeps its
+// colour across posts.
+var discordColors = []int{0x326CE5, 0x0F9D58, 0xF4B400, 0xDB4437, 0x7E57C2, 0x00ACC1, 0xFF7043, 0x8D6E63}
+
+// Discord posts items as embeds to a channel webhook.
+type Discord struct {
+	WebhookURL string
+	Client     *http.Client // http.DefaultClient if nil
+	Dest       string       // destination name; "discord" if empty
+}
+
+func (d *Discord) Name() string {
+	if d.Dest == "" {
+		return "discord"
+	}
+	return d.Dest
+}
+
+// Publish posts it and returns the Discord message id. A 429 is retried
+// after the retry_after Discord asks for, up to discordAttempts times.
+func (d *Discord) Publish(ctx context.Context, it store.Item) (Receipt, error) {
+	body, err := json.Marshal(RenderDiscord(it))
+	if err != nil {
+		return Receipt{}, err
+	}
+	// wait=true makes Discord return the created message.
+	u, err := url.Parse(d.WebhookURL)
+	if err != nil {
+		return Receipt{}, err
+	}
+	q := u.Query()
+	q.Set("wait", "true")
+	u.RawQuery = q.Encode()
+
+	for attempt := 1; ; attempt++ {
+		id, wait, err := d.send(ctx, u.String(), body)
+		if err == nil {
+			return Receipt{RemoteID: id}, nil
+		}
+		if wait == 0 || attempt == discordAttempts {
+			return Receipt{}, err
+		}
+		select {
+		case <-ctx.Done():
+			return Receipt{}, ctx.Err()
+		case <-time.After(wait):
+		}
+	}
+}
+
+// send makes one request. On a 429 it returns how long to wait before
+// trying again.
+func (d *Discord) send(ctx context.Context, u string, body []byte) (id string, wait time.Duration, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := d.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return "", discordRetryAfter(resp.Header, raw), fmt.Errorf("discord webhook: rate limited")
+	case resp.StatusCode/100 != 2:
+		return "", 0, statusError("discord webhook", resp, raw)
+	case len(bytes.TrimSpace(raw)) == 0:
+		// A 204: the message went out but Discord did not return it.
+		return "", 0, nil
+	}
+	var msg struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(raw, &msg); err != nil {
+		return "", 0, fmt.Errorf("discord webhook: decode response: %w", err)
+	}
+	return msg.ID, 0, nil
+}
+
+// discordRetryAfter reads the retry_after seconds from a 429 body, falling
+// back to the Retry-After header and then to one second.
+func discordRetryAfter(h http.Header, body []byte) time.Duration {
+	var rl struct {
+		RetryAfter float64 `json:"retry_after"`
+	}
+	if json.Unmarshal(body, &rl) == nil && rl.RetryAfter > 0 {
+		return time.Duration(rl.RetryAfter * float64(time.Second))
+	}
+	if s, err := strconv.ParseFloat(h.Get("Retry-After"), 64); err == nil && s > 0 {
+		return time.Duration(s * float64(time.Second))
+	}
+	return time.Second
+}
+
+// DiscordMessage is a webhook payload.
+type DiscordMessage struct {
+	Embeds []DiscordEmbed `json:"embeds"`
+}
+
+type DiscordEmbed struct {
+	Title       string         `json:"title"`
+	URL         string         `json:"url,omitempty"`
+	Description string         `json:"description,omitempty"`
+	Color       int            `json:"color,omitempty"`
+	Timestamp   string         `json:"timestamp,omitempty"`
+	Footer      *DiscordFooter `json:"footer,omitempty"`
+}
+
+type DiscordFooter struct {
+	Text string `json:"text"`
+}
+
+// RenderDiscord builds a single embed for it, coloured by its first tag.
+func RenderDiscord(it store.Item) DiscordMessage {
+	e := DiscordEmbed{
+		Title:       truncate(it.Title, discordTitleMax),
+		URL:         it.URL,
+		Description: truncate(it.Summary, discordDescriptionMax),
+		Color:       tagColor(it.Tags),
+	}
+	if !it.PublishedAt.IsZero() {
+		e.Timestamp = it.PublishedAt.UTC().Format(time.RFC3339)
+	}
+	if it.Source != "" {
+		e.Footer = &DiscordFooter{Text: truncate(it.Source, discordFooterMax)}
+	}
+	return DiscordMessage{Embeds: []DiscordEmbed{e}}
+}
+
+// tagColor picks a palette colour from the first of the comma-separated
+// tags, or 0 (Discord's default) when there are none.
+func tagColor(tags string) int {
+	first, _, _ := strings.Cut(tags, ",")
+	first = strings.ToLower(strings.TrimSpace(first))
+	if first == "" {
+		return 0
+	}
+	h := fnv.New32a()
+	h.Write([]byte(first))
+	return discordColors[h.Sum32()%uint32(len(discordColors))]
+}
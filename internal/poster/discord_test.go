@@ -0,0 +1,73 @@
+package poster
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+// TestDiscordRateLimit answers the first send with a 429 and the second
+// with success: the message goes out once, after the wait Discord asked for.
+func TestDiscordRateLimit(t *testing.T) {
+	tests := []struct {
+		name    string
+		limited func(w http.ResponseWriter)
+		ok      func(w http.ResponseWriter)
+		wantID  string
+	}{
+		{
+			name: "retry_after",
+			limited: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusTooManyRequests)
+				fmt.Fprint(w, `{"message": "You are being rate limited.", "retry_after": 0.15, "global": false}`)
+			},
+			ok: func(w http.ResponseWriter) { w.WriteHeader(http.StatusNoContent) },
+		},
+		{
+			name: "Retry-After header",
+			limited: func(w http.ResponseWriter) {
+				w.Header().Set("Retry-After", "0.15")
+				w.WriteHeader(http.StatusTooManyRequests)
+				fmt.Fprint(w, "slow down")
+			},
+			ok:     func(w http.ResponseWriter) { fmt.Fprint(w, `{"id": "1234"}`) },
+			wantID: "1234",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			var sent []time.Time
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				sent = append(sent, time.Now())
+				if calls == 1 {
+					tt.limited(w)
+					return
+				}
+				tt.ok(w)
+			}))
+			defer srv.Close()
+
+			d := &Discord{WebhookURL: srv.URL}
+			rc, err := d.Publish(context.Background(), store.Item{Title: "t", URL: "https://example.com"})
+			if err != nil {
+				t.Fatalf("Publish = %v", err)
+			}
+			if calls != 2 {
+				t.Fatalf("%d sends, want one retry after the 429", calls)
+			}
+			if wait := sent[1].Sub(sent[0]); wait < 150*time.Millisecond {
+				t.Errorf("retried after %v, want the 150ms asked for", wait)
+			}
+			if rc.RemoteID != tt.wantID {
+				t.Errorf("RemoteID = %q, want %q", rc.RemoteID, tt.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package poster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+// DryRun writes the exact messages TG would send, with their scores, to W
+// instead of sending them.
+type DryRun struct {
+	W         io.Writer
+	ParseMode string    // as on TG
+	Template  *Template // as on TG; DefaultTemplate(ParseMode) if nil
+	n         int
+}
+
+func (d *DryRun) Name() string { return "dry-run" }
+
+func (d *DryRun) Publish(ctx context.Context, it store.Item) (Receipt, error) {
+	d.n++
+	id := "new"
+	if it.ID != 0 {
+		id = fmt.Sprint(it.ID)
+	}
+	tmpl := d.Template
+	if tmpl == nil {
+		tmpl = DefaultTemplate(d.ParseMode)
+	}
+	text, err := tmpl.Execute(it)
+	if it.Image != "" {
+		text, err = Caption(it, tmpl, d.ParseMode)
+		text = "[photo " + it.Image + "]\n" + text
+	} else if parts := Split(text, d.ParseMode, textMax); len(parts) > 1 {
+		for i := range parts {
+			parts[i] = fmt.Sprintf("[part %d/%d]\n%s", i+1, len(parts), parts[i])
+		}
+		text = strings.Join(parts, "\n\n")
+	}
+	if err != nil {
+		return Receipt{}, err
+	}
+	_, err = fmt.Fprintf(d.W, "--- #%d  id=%s  score=%.3f  source=%s\nscore detail: %s\n\n%s\n\n",
+		d.n, id, it.Score, it.Source, it.ScoreDetail, text)
+	return Receipt{}, err
+}
@@ -0,0 +1,215 @@
+package poster
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	htmltemplate "html/template"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/smtp"
+	"net/textproto"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+// Digest groups items into one HTML + plaintext email and sends it over
+// SMTP. Unlike the other destinations it is not a Publisher: it is sent on
+// its own schedule with everything selected since the previous digest.
+type Digest struct {
+	Addr    string    // SMTP server host:port
+	Auth    smtp.Auth // nil for servers without authentication
+	From    string
+	To      []string
+	Subject string // defaultDigestSubject if empty
+	GroupBy string // "tag" (first tag of each item) or "source"
+	Dest    string // destination name; "email" if empty
+}
+
+const defaultDigestSubject = "Cloud native digest"
+
+// DigestGroup is one section of a digest.
+type DigestGroup struct {
+	Name  string
+	Items []store.Item
+}
+
+func (d *Digest) Name() string {
+	if d.Dest == "" {
+		return "email"
+	}
+	return d.Dest
+}
+
+// Groups sorts items into sections named after their source or first tag,
+// in name order with the best-scored items first. Untagged items go under
+// "Other".
+func (d *Digest) Groups(items []store.Item) []DigestGroup {
+	byName := map[string][]store.Item{}
+	for _, it := range items {
+		key := it.Source
+		if d.GroupBy != "source" {
+			key, _, _ = strings.Cut(it.Tags, ",")
+			key = strings.TrimSpace(key)
+		}
+		if key == "" {
+			key = "Other"
+		}
+		byName[key] = append(byName[key], it)
+	}
+	out := make([]DigestGroup, 0, len(byName))
+	for name, its := range byName {
+		sort.SliceStable(its, func(i, j int) bool { return its[i].Score > its[j].Score })
+		out = append(out, DigestGroup{Name: name, Items: its})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		// Keep the catch-all section last.
+		if (out[i].Name == "Other") != (out[j].Name == "Other") {
+			return out[j].Name == "Other"
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
+
+var digestText = template.Must(template.New("text").Parse(`{{.Subject}} - {{.Date}}
+{{range .Groups}}
+== {{.Name}} ==
+{{range .Items}}
+* {{.Title}}
+  {{.URL}}
+{{- if .Summary}}
+  {{.Summary}}
+{{- end}}
+  ({{.Source}})
+{{end}}{{end}}`))
+
+var digestHTML = htmltemplate.Must(htmltemplate.New("html").Parse(`<!DOCTYPE html>
+<html><body style="font-family:sans-serif;max-width:640px">
+<h1>{{.Subject}}</h1>
+<p style="color:#666">{{.Date}}</p>
+{{range .Groups}}<h2>{{.Name}}</h2>
+<ul>
+{{range .Items}}<li style="margin-bottom:12px"><a href="{{.URL}}"><strong>{{.Title}}</strong></a>
+{{if .Summary}}<br>{{.Summary}}{{end}}
+<br><small style="color:#666">{{.Source}}</small></li>
+{{end}}</ul>
+{{end}}</body></html>
+`))
+
+// RenderText returns the plaintext body of the digest.
+func (d *Digest) RenderText(items []store.Item, now time.Time) (string, error) {
+	var b strings.Builder
+	err := digestText.Execute(&b, d.view(items, now))
+	return b.String(), err
+}
+
+// RenderHTML returns the HTML body of the digest.
+func (d *Digest) RenderHTML(items []store.Item, now time.Time) (string, error) {
+	var b strings.Builder
+	err := digestHTML.Execute(&b, d.view(items, now))
+	return b.String(), err
+}
+
+func (d *Digest) view(items []store.Item, now time.Time) any {
+	return struct {
+		Subject string
+		Date    string
+		Groups  []DigestGroup
+	}{d.subject(), now.Format("Monday, 2 January 2006"), d.Groups(items)}
+}
+
+func (d *Digest) subject() string {
+	if d.Subject == "" {
+		return defaultDigestSubject
+	}
+	return d.Subject
+}
+
+// Message builds the complete multipart/alternative email for items.
+func (d *Digest) Message(items []store.Item, now time.Time) ([]byte, error) {
+	text, err := d.RenderText(items, now)
+	if err != nil {
+		return nil, err
+	}
+	html, err := d.RenderHTML(items, now)
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct{ ctype, content string }{
+		{"text/plain; charset=utf-8", text},
+		{"text/html; charset=utf-8", html},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.ctype},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	hdr := []struct{ k, v string }{
+		{"From", d.From},
+		{"To", strings.Join(d.To, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", d.subject())},
+		{"Date", now.Format(time.RFC1123Z)},
+		{"Message-ID", messageID(d.From)},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + mw.Boundary()},
+	}
+	for _, h := range hdr {
+		fmt.Fprintf(&msg, "%s: %s\r\n", h.k, h.v)
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// Send mails the digest of items. It does nothing for an empty batch.
+func (d *Digest) Send(ctx context.Context, items []store.Item) error {
+	if len(items) == 0 {
+		return nil
+	}
+	msg, err := d.Message(items, time.Now())
+	if err != nil {
+		return err
+	}
+	// net/smtp has no context support; at least do not start a send for a
+	// cancelled run.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return smtp.SendMail(d.Addr, d.Auth, d.From, d.To, msg)
+}
+
+func messageID(from string) string {
+	domain := "localhost"
+	if i := strings.LastIndex(from, "@"); i >= 0 {
+		domain = strings.Trim(from[i+1:], "> ")
+	}
+	var b [12]byte
+	_, _ = rand.Read(b[:])
+	return "<" + hex.EncodeToString(b[:]) + "@" + domain + ">"
+}
@@ -0,0 +1,170 @@
+package poster
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+// smtpStub accepts one SMTP session on a local port and hands the DATA
+// of the message it received to the returned channel.
+func smtpStub(t *testing.T) (addr string, msgs <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	ch := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+		reply("220 localhost ESMTP stub")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.TrimSpace(line)); {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "DATA"):
+				reply("354 end with <CRLF>.<CRLF>")
+				var b strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					b.WriteString(strings.TrimPrefix(l, "."))
+				}
+				ch <- b.String()
+				reply("250 queued")
+			case strings.HasPrefix(cmd, "QUIT"):
+				reply("221 bye")
+				return
+			default: // MAIL, RCPT, RSET, NOOP
+				reply("250 OK")
+			}
+		}
+	}()
+	return ln.Addr().String(), ch
+}
+
+var digestItems = []store.Item{
+	{Source: "CNCF Blog", Title: "Kubernetes 1.31", URL: "https://example.com/k8s", Summary: "Forty-five enhancements.", Tags: "kubernetes,release", Score: 0.7},
+	{Source: "Kubernetes Blog", Title: "Sidecars go GA", URL: "https://example.com/sidecars", Tags: "kubernetes", Score: 0.9},
+	{Source: "CNCF Blog", Title: "Prometheus 3.0", URL: "https://example.com/prom", Tags: "observability", Score: 0.8},
+	{Source: "Kubernetes Blog", Title: "Untagged post", URL: "https://example.com/other", Score: 0.6},
+}
+
+func TestDigestSendMultipart(t *testing.T) {
+	addr, msgs := smtpStub(t)
+	d := &Digest{Addr: addr, From: "CNCG Bot <bot@example.com>", To: []string{"team@example.com"}, Subject: "Weekly"}
+	if err := d.Send(context.Background(), digestItems); err != nil {
+		t.Fatalf("Send = %v", err)
+	}
+
+	var raw string
+	select {
+	case raw = <-msgs:
+	case <-time.After(5 * time.Second):
+		t.Fatal("stub received no message")
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.Header.Get("To"); got != "team@example.com" {
+		t.Errorf("To = %q", got)
+	}
+	if got := msg.Header.Get("Subject"); got != "Weekly" {
+		t.Errorf("Subject = %q", got)
+	}
+	mt, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mt != "multipart/alternative" {
+		t.Fatalf("Content-Type = %q, %v; want multipart/alternative", msg.Header.Get("Content-Type"), err)
+	}
+
+	parts := map[string]string{}
+	var order []string
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ := io.ReadAll(p) // quoted-printable is decoded by NextPart
+		ct, _, _ := mime.ParseMediaType(p.Header.Get("Content-Type"))
+		parts[ct] = string(body)
+		order = append(order, ct)
+	}
+	if strings.Join(order, ",") != "text/plain,text/html" {
+		t.Fatalf("parts = %v, want text/plain then text/html", order)
+	}
+
+	text := parts["text/plain"]
+	wantOrder := []string{"== kubernetes ==", "Sidecars go GA", "Kubernetes 1.31", "== observability ==", "Prometheus 3.0", "== Other ==", "Untagged post"}
+	at := 0
+	for _, w := range wantOrder {
+		i := strings.Index(text[at:], w)
+		if i < 0 {
+			t.Fatalf("text part lacks %q after offset %d:\n%s", w, at, text)
+		}
+		at += i + len(w)
+	}
+	if html := parts["text/html"]; !strings.Contains(html, `<a href="https://example.com/sidecars"><strong>Sidecars go GA</strong></a>`) {
+		t.Errorf("html part lacks the linked title:\n%s", html)
+	}
+}
+
+func TestDigestGroups(t *testing.T) {
+	tests := []struct {
+		groupBy string
+		want    string
+	}{
+		{"tag", "kubernetes[Sidecars go GA,Kubernetes 1.31] observability[Prometheus 3.0] Other[Untagged post]"},
+		{"source", "CNCF Blog[Prometheus 3.0,Kubernetes 1.31] Kubernetes Blog[Sidecars go GA,Untagged post]"},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, g := range (&Digest{GroupBy: tt.groupBy}).Groups(digestItems) {
+			var titles []string
+			for _, it := range g.Items {
+				titles = append(titles, it.Title)
+			}
+			got = append(got, g.Name+"["+strings.Join(titles, ",")+"]")
+		}
+		if s := strings.Join(got, " "); s != tt.want {
+			t.Errorf("GroupBy %s:\n got %s\nwant %s", tt.groupBy, s, tt.want)
+		}
+	}
+}
+
+func TestDigestSendEmpty(t *testing.T) {
+	d := &Digest{Addr: "127.0.0.1:1"} // nothing listens; Send must not dial
+	if err := d.Send(context.Background(), nil); err != nil {
+		t.Errorf("Send(nil) = %v, want nil", err)
+	}
+}
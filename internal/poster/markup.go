@@ -0,0 +1,59 @@
+package poster
+
+import (
+	"fmt"
+
+	"github.com/LibenHailu/cncg-bot/internal/util"
+)
+
+// Markup knows how to write formatted text for one Telegram parse mode.
+// Text and link targets escape differently: MarkdownV2, for instance,
+// reserves 19 characters in text but only ")" and "\" inside (...).
+type Markup struct {
+	Escape    func(string) string // plain text
+	EscapeURL func(string) string // a link target
+	link      string              // format of a link: label, escaped URL
+	bold      string
+	italic    string
+}
+
+var (
+	MarkdownV2 = Markup{
+		Escape: util.EscapeTelegram, EscapeURL: util.EscapeTelegramURL,
+		link: "[%s](%s)", bold: "*%s*", italic: "_%s_",
+	}
+	HTML = Markup{
+		Escape: util.EscapeTelegramHTML, EscapeURL: util.EscapeTelegramHTML,
+		link: `<a href="%[2]s">%[1]s</a>`, bold: "<b>%s</b>", italic: "<i>%s</i>",
+	}
+	// Plain has no formatting; a link is its label followed by the URL.
+	Plain = Markup{
+		Escape: identity, EscapeURL: identity,
+		link: "%s\n%s", bold: "%s", italic: "%s",
+	}
+)
+
+// MarkupFor returns the Markup of a Telegram parse mode ("" is plain text).
+func MarkupFor(parseMode string) (Markup, error) {
+	switch parseMode {
+	case "MarkdownV2":
+		return MarkdownV2, nil
+	case "HTML":
+		return HTML, nil
+	case "":
+		return Plain, nil
+	}
+	return Markup{}, fmt.Errorf("unsupported parse mode %q", parseMode)
+}
+
+// Link formats a link around label, which must already be escaped (it may
+// contain formatting); url is escaped here.
+func (m Markup) Link(label, url string) string {
+	return fmt.Sprintf(m.link, label, m.EscapeURL(url))
+}
+
+// Bold and Italic wrap already-escaped text.
+func (m Markup) Bold(s string) string   { return fmt.Sprintf(m.bold, s) }
+func (m Markup) Italic(s string) string { return fmt.Sprintf(m.italic, s) }
+
+func identity(s string) string { return s }
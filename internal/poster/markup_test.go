@@ -0,0 +1,61 @@
+package poster
+
+import (
+	"testing"
+
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+// TestRenderEscaping renders an item full of reserved characters in every
+// parse mode: text is escaped as text and the URL as a link target.
+func TestRenderEscaping(t *testing.T) {
+	it := store.Item{
+		Title:   "Go 1.23 (rc_1) <beta>",
+		URL:     "https://example.com/a_(b)?x=1&y=2",
+		Summary: "Faster *builds* & more.",
+		Source:  "Go Blog",
+		Tags:    "go,release",
+	}
+	tests := []struct {
+		mode, want string
+	}{
+		{"MarkdownV2", "[*Go 1\\.23 \\(rc\\_1\\) <beta\\>*](https://example.com/a_(b\\)?x=1&y=2)\n\n" +
+			"Faster \\*builds\\* & more\\.\n\n" +
+			"_Source:_ Go Blog\n\\#go \\#release"},
+		{"HTML", `<a href="https://example.com/a_(b)?x=1&amp;y=2"><b>Go 1.23 (rc_1) &lt;beta&gt;</b></a>` + "\n\n" +
+			"Faster *builds* &amp; more.\n\n" +
+			"<i>Source:</i> Go Blog\n#go #release"},
+		{"", "Go 1.23 (rc_1) <beta>\nhttps://example.com/a_(b)?x=1&y=2\n\n" +
+			"Faster *builds* & more.\n\n" +
+			"Source: Go Blog\n#go #release"},
+	}
+	for _, tt := range tests {
+		got, err := (&TG{ParseMode: tt.mode}).template().Execute(it)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%q mode:\n got %q\nwant %q", tt.mode, got, tt.want)
+		}
+	}
+}
+
+func TestMarkupLink(t *testing.T) {
+	// The label comes in escaped and is left alone; the URL is escaped as
+	// a link target, not as text.
+	const url = `https://x.test/p_(1)\q"`
+	tests := []struct {
+		name string
+		m    Markup
+		want string
+	}{
+		{"MarkdownV2", MarkdownV2, `[a\.b](https://x.test/p_(1\)\\q")`},
+		{"HTML", HTML, `<a href="https://x.test/p_(1)\q&quot;">a\.b</a>`},
+		{"Plain", Plain, "a\\.b\n" + url},
+	}
+	for _, tt := range tests {
+		if got := tt.m.Link(`a\.b`, url); got != tt.want {
+			t.Errorf("%s Link = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package poster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+const (
+	// mastodonMax is the default status limit of a Mastodon instance.
+	mastodonMax = 500
+	// mastodonURLLen is what any link counts as towards mastodonMax,
+	// whatever its real length.
+	mastodonURLLen = 23
+)
+
+// Mastodon creates statuses on a Mastodon instance.
+type Mastodon struct {
+	Server      string // instance base URL, e.g. https://hachyderm.io
+	AccessToken string // needs the write:statuses scope
+	Client      *http.Client
+	Dest        string    // destination name; "mastodon" if empty
+	Template    *Template // status text; RenderMastodon's layout if nil
+}
+
+func (m *Mastodon) Name() string {
+	if m.Dest == "" {
+		return "mastodon"
+	}
+	return m.Dest
+}
+
+// Publish posts it as a public status and returns the status id. The item
+// hash is sent as the Idempotency-Key so a retried request cannot create
+// the status twice.
+func (m *Mastodon) Publish(ctx context.Context, it store.Item) (Receipt, error) {
+	text := RenderMastodon(it)
+	if m.Template != nil {
+		var err error
+		if text, err = m.render(it); err != nil {
+			return Receipt{}, err
+		}
+	}
+	body, err := json.Marshal(map[string]string{"status": text})
+	if err != nil {
+		return Receipt{}, err
+	}
+	endpoint := strings.TrimRight(m.Server, "/") + "/api/v1/statuses"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return Receipt{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+m.AccessToken)
+	if it.Hash != "" {
+		req.Header.Set("Idempotency-Key", it.Hash)
+	}
+
+	client := m.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Receipt{}, err
+	}
+	defer resp.Body.Close()
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode/100 != 2 {
+		return Receipt{}, statusError("mastodon", resp, raw)
+	}
+	var status struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(raw, &status); err != nil {
+		return Receipt{}, fmt.Errorf("mastodon: decode response: %w", err)
+	}
+	return Receipt{RemoteID: status.ID}, nil
+}
+
+// render executes Template, shortening the summary to stay within
+// mastodonMax.
+func (m *Mastodon) render(it store.Item) (string, error) {
+	length := func(s string) int { return mastodonLen(s, it.URL) }
+	text, err := fit(it, mastodonMax, length, m.Template.Execute)
+	if err != nil {
+		return "", err
+	}
+	if over := length(text) - mastodonMax; over > 0 {
+		text = truncateWords(text, runeLen(text)-over)
+	}
+	return text, nil
+}
+
+// RenderMastodon lays out it as title, summary, link and hashtags within
+// mastodonMax characters. The summary is shortened first (at a word
+// boundary), then hashtags are dropped from the end, and only then is the
+// title cut.
+func RenderMastodon(it store.Item) string {
+	title := strings.TrimSpace(it.Title)
+	summary := strings.TrimSpace(it.Summary)
+	tags := Hashtags(it.Tags)
+
+	compose := func(title, summary string, tags []string) string {
+		parts := []string{title}
+		if summary != "" {
+			parts = append(parts, summary)
+		}
+		parts = append(parts, it.URL)
+		if len(tags) > 0 {
+			parts = append(parts, strings.Join(tags, " "))
+		}
+		return strings.Join(parts, "\n\n")
+	}
+	fits := func(s string) bool { return mastodonLen(s, it.URL) <= mastodonMax }
+
+	if s := compose(title, summary, tags); fits(s) {
+		return s
+	}
+	// Room left for the summary once everything else is in place; below
+	// a few words a summary is not worth keeping.
+	if summary != "" {
+		room := mastodonMax - mastodonLen(compose(title, "", tags), it.URL) - len("\n\n")
+		if room >= 40 {
+			return compose(title, truncateWords(summary, room), tags)
+		}
+	}
+	for len(tags) > 0 && !fits(compose(title, "", tags)) {
+		tags = tags[:len(tags)-1]
+	}
+	if s := compose(title, "", tags); fits(s) {
+		return s
+	}
+	room := mastodonMax - mastodonLen(compose("", "", nil), it.URL)
+	return compose(truncateWords(title, room), "", nil)
+}
+
+// mastodonLen counts s the way Mastodon does, with link u weighing
+// mastodonURLLen characters.
+func mastodonLen(s, u string) int {
+	n := len([]rune(s))
+	if u != "" && strings.Contains(s, u) {
+		n += mastodonURLLen - len([]rune(u))
+	}
+	return n
+}
+
+// Hashtags turns comma-separated tags into Mastodon hashtags. Multi-word
+// tags are CamelCased ("cloud native" -> #CloudNative) since hashtags only
+// allow letters, digits and underscores. Duplicates are dropped.
+func Hashtags(tags string) []string {
+	var out []string
+	seen := map[string]bool{}
+	for _, tag := range strings.Split(tags, ",") {
+		words := strings.FieldsFunc(tag, func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+		})
+		if len(words) == 0 {
+			continue
+		}
+		var b strings.Builder
+		for _, w := range words {
+			if len(words) > 1 {
+				r := []rune(w)
+				r[0] = unicode.ToUpper(r[0])
+				w = string(r)
+			}
+			b.WriteString(w)
+		}
+		h := b.String()
+		if strings.IndexFunc(h, unicode.IsLetter) < 0 || seen[strings.ToLower(h)] {
+			continue
+		}
+		seen[strings.ToLower(h)] = true
+		out = append(out, "#"+h)
+	}
+	return out
+}
+
+// truncateWords shortens s to at most n runes, cutting at the last word
+// boundary and ending in an ellipsis.
+func truncateWords(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	cut := truncate(s, n)
+	body := strings.TrimSuffix(cut, "…")
+	if i := strings.LastIndexFunc(body, unicode.IsSpace); i > len(body)/2 {
+		body = body[:i]
+	}
+	return strings.TrimRightFunc(body, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	}) + "…"
+}
@@ -0,0 +1,107 @@
+package poster
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+func TestRenderMastodon(t *testing.T) {
+	const link = "https://kubernetes.io/blog/2024/08/13/kubernetes-v1-31-release/"
+	words := func(w string, n int) string { return strings.TrimSpace(strings.Repeat(w+" ", n)) }
+	tags := "kubernetes,release,cloud native,sig-node,sig-storage,sig-network,sig-auth,sig-apps,sig-cli,sig-scheduling"
+	allTags := strings.Join(Hashtags(tags), " ")
+
+	tests := []struct {
+		name    string
+		it      store.Item
+		summary string // "" when dropped, "…" when shortened
+		tags    string // hashtags kept
+		title   string // "" when whole
+	}{
+		{
+			name:    "fits",
+			it:      store.Item{Title: "Kubernetes v1.31: Elli", Summary: "45 enhancements.", Tags: tags},
+			summary: "45 enhancements.",
+			tags:    allTags,
+		},
+		{
+			// 462 characters with the link counted as 23, 502 as written.
+			name:    "link counts 23",
+			it:      store.Item{Title: "Kubernetes v1.31: Elli", Summary: words("node", 80) + ".", Tags: "kubernetes"},
+			summary: words("node", 80) + ".",
+			tags:    "#kubernetes",
+		},
+		{
+			name:    "summary shortened",
+			it:      store.Item{Title: "Kubernetes v1.31: Elli", Summary: words("enhancement", 60), Tags: tags},
+			summary: "…",
+			tags:    allTags,
+		},
+		{
+			// Too little room left for a summary: it goes, and hashtags
+			// are dropped from the end until the rest fits.
+			name: "hashtags dropped",
+			it: store.Item{
+				Title: words("Elli", 85), Summary: words("enhancement", 10),
+				Tags: tags + ",kubelet,kubeadm,kubectl,etcd,containerd,cri-o,gateway api,ingress,dra",
+			},
+			tags: "#kubernetes #release #CloudNative #SigNode",
+		},
+		{
+			name:  "title cut",
+			it:    store.Item{Title: words("Elli", 130), Summary: "45 enhancements.", Tags: tags},
+			title: "…",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.it.URL = link
+			got := RenderMastodon(tt.it)
+			if n := mastodonLen(got, link); n > mastodonMax {
+				t.Errorf("status counts %d characters, want at most %d:\n%s", n, mastodonMax, got)
+			}
+
+			parts := strings.Split(got, "\n\n")
+			want := 2 // title and link
+			if tt.summary != "" {
+				want++
+			}
+			if tt.tags != "" {
+				want++
+			}
+			if len(parts) != want {
+				t.Fatalf("%d paragraphs, want %d:\n%s", len(parts), want, got)
+			}
+			title, rest := parts[0], parts[1:]
+			if tt.title == "" && title != tt.it.Title || tt.title != "" && !strings.HasSuffix(title, tt.title) {
+				t.Errorf("title = %q", title)
+			}
+			if tt.summary != "" {
+				summary := rest[0]
+				rest = rest[1:]
+				if tt.summary == "…" {
+					if !strings.HasSuffix(summary, "…") || !strings.HasPrefix(tt.it.Summary, strings.TrimSuffix(summary, "…")) {
+						t.Errorf("summary = %q, want a shortened %q", summary, tt.it.Summary)
+					}
+				} else if summary != tt.summary {
+					t.Errorf("summary = %q, want %q", summary, tt.summary)
+				}
+			}
+			if rest[0] != link {
+				t.Errorf("link = %q, want %q", rest[0], link)
+			}
+			if tt.tags != "" && rest[1] != tt.tags {
+				t.Errorf("hashtags = %q, want %q", rest[1], tt.tags)
+			}
+		})
+	}
+}
+
+func TestHashtags(t *testing.T) {
+	got := strings.Join(Hashtags("kubernetes, cloud native,Kubernetes,sig-node,1.31,,c++"), " ")
+	if want := "#kubernetes #CloudNative #SigNode #c"; got != want {
+		t.Errorf("Hashtags = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,67 @@
+package poster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+// Publisher delivers items to one destination (a Telegram channel, a Slack
+// webhook, ...). The handler loops over every configured Publisher and
+// records each successful delivery in the store under Name.
+type Publisher interface {
+	// Name identifies the destination; it is unique among the configured
+	// publishers and stable across runs.
+	Name() string
+	Publish(ctx context.Context, it store.Item) (Receipt, error)
+}
+
+// Resumer is implemented by publishers that send an item as several
+// messages and can finish a delivery a failed attempt left halfway, given
+// the receipt of what went out then, instead of sending it all again.
+type Resumer interface {
+	Resume(ctx context.Context, it store.Item, partial Receipt) (Receipt, error)
+}
+
+// Receipt describes a successful delivery.
+type Receipt struct {
+	RemoteID string    // message or status id at the destination, if it has one
+	Replies  []string  // ids of the follow-up messages when the item took several
+	At       time.Time // when the destination accepted it; zero means now
+}
+
+// ErrPermanent is wrapped by delivery errors that retrying cannot fix for
+// this item, such as rejected markup. Callers give up on the item for that
+// destination instead of trying again next run.
+var ErrPermanent = errors.New("permanent failure")
+
+// ErrDestination is wrapped by errors about the destination rather than the
+// item: a revoked token, a deleted webhook, a chat the bot was removed from.
+// Every other item would fail the same way, so callers stop sending there
+// for the run and keep the items to retry once the destination is fixed.
+var ErrDestination = errors.New("destination unavailable")
+
+// statusError describes a non-2xx response. Authentication and not-found
+// answers wrap ErrDestination; other client errors except 429 wrap
+// ErrPermanent: sending the same request again gets the same answer.
+func statusError(what string, resp *http.Response, body []byte) error {
+	err := fmt.Errorf("%s: %s", what, resp.Status)
+	if m := strings.TrimSpace(string(body)); m != "" {
+		err = fmt.Errorf("%s: %s: %s", what, resp.Status, m)
+	}
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound, http.StatusGone:
+		return fmt.Errorf("%w: %v", ErrDestination, err)
+	case http.StatusTooManyRequests:
+		return err
+	}
+	if resp.StatusCode/100 == 4 {
+		return fmt.Errorf("%w: %v", ErrPermanent, err)
+	}
+	return err
+}
@@ -0,0 +1,55 @@
+package poster
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+// TestClientErrors checks that the HTTP publishers give up on 4xx answers
+// other than 429, blame the destination for auth and not-found answers, and
+// keep retrying server errors.
+func TestClientErrors(t *testing.T) {
+	publishers := map[string]func(url string) Publisher{
+		"slack":    func(u string) Publisher { return &Slack{WebhookURL: u} },
+		"discord":  func(u string) Publisher { return &Discord{WebhookURL: u} },
+		"mastodon": func(u string) Publisher { return &Mastodon{Server: u, AccessToken: "t"} },
+		"webhook":  func(u string) Publisher { return &Webhook{URL: u, MaxAttempts: 1} },
+	}
+	tests := []struct {
+		code        int
+		permanent   bool
+		destination bool
+	}{
+		{http.StatusBadRequest, true, false},
+		{http.StatusUnprocessableEntity, true, false},
+		{http.StatusUnauthorized, false, true},
+		{http.StatusForbidden, false, true},
+		{http.StatusNotFound, false, true},
+		{http.StatusInternalServerError, false, false},
+		{http.StatusBadGateway, false, false},
+	}
+	for name, newPub := range publishers {
+		for _, tt := range tests {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "nope", tt.code)
+			}))
+			_, err := newPub(srv.URL).Publish(context.Background(), store.Item{Title: "t", URL: "https://example.com"})
+			srv.Close()
+			if err == nil {
+				t.Errorf("%s %d: Publish succeeded", name, tt.code)
+				continue
+			}
+			if got := errors.Is(err, ErrPermanent); got != tt.permanent {
+				t.Errorf("%s %d: permanent = %v, want %v (%v)", name, tt.code, got, tt.permanent, err)
+			}
+			if got := errors.Is(err, ErrDestination); got != tt.destination {
+				t.Errorf("%s %d: destination = %v, want %v (%v)", name, tt.code, got, tt.destination, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package poster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/LibenHailu/cncg-bot/internal/store"
+	"github.com/LibenHailu/cncg-bot/internal/util"
+)
+
+// Slack limits: section text is capped at 3000 characters and a context
+// block holds at most 10 elements.
+const (
+	slackSectionMax = 3000
+	slackContextMax = 10
+)
+
+// Slack posts items as Block Kit messages to an incoming webhook.
+type Slack struct {
+	WebhookURL string
+	Client     *http.Client // http.DefaultClient if nil
+	Dest       string       // destination name; "slack" if empty
+}
+
+func (s *Slack) Name() string {
+	if s.Dest == "" {
+		return "slack"
+	}
+	return s.Dest
+}
+
+// Publish posts it to the webhook. Incoming webhooks do not return a
+// message id, so the Receipt is empty.
+func (s *Slack) Publish(ctx context.Context, it store.Item) (Receipt, error) {
+	body, err := json.Marshal(RenderSlack(it))
+	if err != nil {
+		return Receipt{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return Receipt{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Receipt{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return Receipt{}, statusError("slack webhook", resp, msg)
+	}
+	return Receipt{}, nil
+}
+
+// SlackMessage is an incoming-webhook payload. Text is the notification
+// fallback shown where blocks cannot be rendered.
+type SlackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []SlackBlock `json:"blocks"`
+}
+
+type SlackBlock struct {
+	Type     string      `json:"type"`
+	Text     *SlackText  `json:"text,omitempty"`
+	Elements []SlackText `json:"elements,omitempty"`
+}
+
+type SlackText struct {
+	Type string `json:"type"` // "mrkdwn" or "plain_text"
+	Text string `json:"text"`
+}
+
+// RenderSlack lays out it as a title link with the summary underneath and
+// a context line holding the source and one chip per tag.
+func RenderSlack(it store.Item) SlackMessage {
+	head := fmt.Sprintf("*<%s|%s>*", slackURL(it.URL), util.EscapeSlack(it.Title))
+	text := head
+	if it.Summary != "" {
+		text += "\n" + truncate(util.EscapeSlack(it.Summary), slackSectionMax-len([]rune(head))-1)
+	}
+
+	ctxBlock := SlackBlock{Type: "context", Elements: []SlackText{
+		{Type: "mrkdwn", Text: "Source: *" + util.EscapeSlack(it.Source) + "*"},
+	}}
+	for _, tag := range strings.Split(it.Tags, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || len(ctxBlock.Elements) == slackContextMax {
+			continue
+		}
+		ctxBlock.Elements = append(ctxBlock.Elements, SlackText{Type: "mrkdwn", Text: "`" + util.EscapeSlack(tag) + "`"})
+	}
+
+	return SlackMessage{
+		Text: util.EscapeSlack(it.Title),
+		Blocks: []SlackBlock{
+			{Type: "section", Text: &SlackText{Type: "mrkdwn", Text: text}},
+			ctxBlock,
+		},
+	}
+}
+
+// slackURL makes u safe inside a <url|label> link: the delimiters are
+// percent-encoded rather than entity-escaped so the link still resolves.
+func slackURL(u string) string {
+	return strings.NewReplacer("<", "%3C", ">", "%3E", "|", "%7C", " ", "%20").Replace(u)
+}
+
+// truncate shortens s to at most n runes, ending in an ellipsis when cut.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	if n <= 1 {
+		return string(r[:max(n, 0)])
+	}
+	return strings.TrimSpace(string(r[:n-1])) + "…"
+}
@@ -0,0 +1,348 @@
+package poster
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// textMax is Telegram's limit on the text of one message. Telegram counts
+// UTF-16 code units of the text after entity parsing, so measuring the raw
+// markup the same way is on the safe side.
+const textMax = 4096
+
+// Split breaks text, formatted in parseMode ("MarkdownV2", "HTML" or ""),
+// into parts of at most max UTF-16 code units. It cuts between paragraphs
+// if it can, else between lines, else between words, and never inside an
+// escape sequence, a MarkdownV2 link target or an HTML tag or entity. Cuts
+// are made where no entity is open; only when an entity (a link included)
+// spans more than a part is it closed at the end of one part and reopened
+// in the next.
+func Split(text, parseMode string, max int) []string {
+	if utf16Len(text) <= max {
+		return []string{text}
+	}
+	units := tokenize(text, parseMode)
+
+	var parts []string
+	var carry []frame // entities open at the start of the next part
+	for start := 0; start < len(units); {
+		for start < len(units) && isSpace(units[start].s) {
+			start++
+		}
+		if start == len(units) {
+			break
+		}
+
+		prefix := openers(carry)
+		size := utf16Len(prefix)
+		stack := append([]frame(nil), carry...)
+
+		type cut struct {
+			end, size, rank int
+			stack           []frame // entities open at end
+		}
+		var safe []cut // boundaries with no entity open
+		var soft []cut // whitespace inside an entity
+		last := cut{end: start}
+		for i := start; i < len(units); i++ {
+			stack = units[i].apply(stack)
+			size += utf16Len(units[i].s)
+			if size+utf16Len(closers(stack)) > max {
+				break
+			}
+			last = cut{end: i + 1, size: size, stack: append([]frame(nil), stack...)}
+			c := last
+			c.rank = boundaryRank(units, start, i)
+			if len(stack) == 0 {
+				safe = append(safe, c)
+			} else if c.rank > 0 {
+				soft = append(soft, c)
+			}
+		}
+
+		if last.end == len(units) {
+			parts = append(parts, strings.TrimRightFunc(prefix+join(units[start:])+closers(last.stack), isSpaceRune))
+			break
+		}
+		if last.end == start {
+			// A single unit (say an HTML link with a huge href) longer
+			// than max: cut it by runes, as there is nothing better to do.
+			r := []rune(units[start].s)
+			room := max - utf16Len(prefix) - utf16Len(closers(carry))
+			n := 1
+			for n < len(r) && utf16Len(string(r[:n+1])) <= room {
+				n++
+			}
+			parts = append(parts, prefix+string(r[:n])+closers(carry))
+			units[start].s = string(r[n:])
+			if units[start].s == "" {
+				start++
+			}
+			continue
+		}
+
+		// Cut where no entity is open if possible, else close and reopen
+		// the open ones, at whitespace if there is any.
+		pick := func(cuts []cut) cut {
+			best := cuts[len(cuts)-1]
+			for _, c := range cuts {
+				if c.size >= max/2 && c.rank >= best.rank {
+					best = c
+				}
+			}
+			return best
+		}
+		chosen := last
+		switch {
+		case len(safe) > 0:
+			chosen = pick(safe)
+		case len(soft) > 0:
+			chosen = pick(soft)
+		}
+		body := units[start:chosen.end]
+		for len(body) > 0 && isSpace(body[len(body)-1].s) {
+			body = body[:len(body)-1]
+		}
+		parts = append(parts, prefix+join(body)+closers(chosen.stack))
+		carry, start = chosen.stack, chosen.end
+	}
+	return parts
+}
+
+// frame is an open entity: how to reopen it and how to close it.
+type frame struct{ open, close string }
+
+// unit is a piece of text that must not be split, and what it does to the
+// stack of open entities.
+type unit struct {
+	s      string
+	push   *frame
+	pop    bool
+	toggle string // MarkdownV2 marker that opens or closes itself
+}
+
+func (u unit) apply(stack []frame) []frame {
+	switch {
+	case u.push != nil:
+		return append(stack, *u.push)
+	case u.pop && len(stack) > 0:
+		return stack[:len(stack)-1]
+	case u.toggle != "":
+		if n := len(stack); n > 0 && stack[n-1].close == u.toggle {
+			return stack[:n-1]
+		}
+		return append(stack, frame{open: u.toggle, close: u.toggle})
+	}
+	return stack
+}
+
+func tokenize(text, parseMode string) []unit {
+	switch parseMode {
+	case "MarkdownV2":
+		return tokenizeMarkdownV2(text)
+	case "HTML":
+		return tokenizeHTML(text)
+	}
+	var out []unit
+	for _, r := range text {
+		out = append(out, unit{s: string(r)})
+	}
+	return out
+}
+
+// mdMarkers are MarkdownV2's self-closing entity markers, longest first.
+var mdMarkers = []string{"```", "||", "__", "*", "_", "~", "`"}
+
+func tokenizeMarkdownV2(text string) []unit {
+	var out []unit
+	code := "" // "`" or "```" while inside code, where only \ and the closer count
+	for i := 0; i < len(text); {
+		rest := text[i:]
+		if rest[0] == '\\' && len(rest) > 1 {
+			_, n := utf8.DecodeRuneInString(rest[1:])
+			out = append(out, unit{s: rest[:1+n]})
+			i += 1 + n
+			continue
+		}
+		if code != "" {
+			if strings.HasPrefix(rest, code) {
+				u := unit{s: code, toggle: code}
+				if code == "```" {
+					// Closes the frame its opener pushed.
+					u.toggle, u.pop = "", true
+				}
+				out = append(out, u)
+				i += len(code)
+				code = ""
+				continue
+			}
+		} else {
+			if n := mdLinkLen(rest); n > 0 {
+				// The label is formatted text like any other; a link too
+				// long for one part is closed and reopened around the cut
+				// like any entity, pointing at the same target.
+				open := strings.IndexByte(rest, '[') + 1
+				end := mdFind(rest, open, "](")
+				out = append(out, unit{s: rest[:open], push: &frame{open: rest[:open], close: rest[end:n]}})
+				out = append(out, tokenizeMarkdownV2(rest[open:end])...)
+				out = append(out, unit{s: rest[end:n], pop: true})
+				i += n
+				continue
+			}
+			if m := mdMarker(rest); m != "" {
+				u := unit{s: m, toggle: m}
+				if m == "```" {
+					// The language line belongs to the opener.
+					if j := strings.IndexByte(rest, '\n'); j >= 0 {
+						u.s = rest[:j+1]
+					}
+					u.push, u.toggle = &frame{open: u.s, close: "```"}, ""
+					code = m
+				} else if m == "`" {
+					code = m
+				}
+				out = append(out, u)
+				i += len(u.s)
+				continue
+			}
+		}
+		_, n := utf8.DecodeRuneInString(rest)
+		out = append(out, unit{s: rest[:n]})
+		i += n
+	}
+	return out
+}
+
+func mdMarker(s string) string {
+	for _, m := range mdMarkers {
+		if strings.HasPrefix(s, m) {
+			return m
+		}
+	}
+	return ""
+}
+
+// mdLinkLen returns the length of the inline link (or custom emoji,
+// "![..](..)") s starts with, or 0.
+func mdLinkLen(s string) int {
+	i := 0
+	if strings.HasPrefix(s, "![") {
+		i = 1
+	}
+	if i >= len(s) || s[i] != '[' {
+		return 0
+	}
+	j := mdFind(s, i+1, "](")
+	if j < 0 {
+		return 0
+	}
+	k := mdFind(s, j+2, ")")
+	if k < 0 {
+		return 0
+	}
+	return k + 1
+}
+
+// mdFind finds the first unescaped sep in s at or after from.
+func mdFind(s string, from int, sep string) int {
+	for i := from; i < len(s); i++ {
+		switch {
+		case s[i] == '\\':
+			i++
+		case strings.HasPrefix(s[i:], sep):
+			return i
+		}
+	}
+	return -1
+}
+
+func tokenizeHTML(text string) []unit {
+	var out []unit
+	for i := 0; i < len(text); {
+		rest := text[i:]
+		switch rest[0] {
+		case '<':
+			if j := strings.IndexByte(rest, '>'); j > 0 {
+				tag := rest[:j+1]
+				u := unit{s: tag}
+				if strings.HasPrefix(tag, "</") {
+					u.pop = true
+				} else if !strings.HasSuffix(tag, "/>") {
+					name := strings.FieldsFunc(tag[1:len(tag)-1], func(r rune) bool { return r == ' ' || r == '\t' || r == '\n' })
+					if len(name) > 0 {
+						u.push = &frame{open: tag, close: "</" + name[0] + ">"}
+					}
+				}
+				out = append(out, u)
+				i += j + 1
+				continue
+			}
+		case '&':
+			if j := strings.IndexByte(rest, ';'); j > 0 && j < 10 {
+				out = append(out, unit{s: rest[:j+1]})
+				i += j + 1
+				continue
+			}
+		}
+		_, n := utf8.DecodeRuneInString(rest)
+		out = append(out, unit{s: rest[:n]})
+		i += n
+	}
+	return out
+}
+
+// boundaryRank rates cutting after units[i]: 3 between paragraphs, 2 at a
+// line end, 1 between words, 0 anywhere else. A cut just before a space is
+// as good as one after it: the space is dropped either way.
+func boundaryRank(units []unit, start, i int) int {
+	switch {
+	case units[i].s == "\n" && i > start && units[i-1].s == "\n":
+		return 3
+	case units[i].s == "\n":
+		return 2
+	case isSpace(units[i].s), i+1 < len(units) && isSpace(units[i+1].s):
+		return 1
+	}
+	return 0
+}
+
+func openers(stack []frame) string {
+	var b strings.Builder
+	for _, f := range stack {
+		b.WriteString(f.open)
+	}
+	return b.String()
+}
+
+func closers(stack []frame) string {
+	var b strings.Builder
+	for i := len(stack) - 1; i >= 0; i-- {
+		b.WriteString(stack[i].close)
+	}
+	return b.String()
+}
+
+func join(units []unit) string {
+	var b strings.Builder
+	for _, u := range units {
+		b.WriteString(u.s)
+	}
+	return b.String()
+}
+
+func isSpace(s string) bool { return s == " " || s == "\n" || s == "\t" }
+
+func isSpaceRune(r rune) bool { return r == ' ' || r == '\n' || r == '\t' }
+
+// utf16Len is the length of s in UTF-16 code units, as Telegram counts.
+func utf16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		if r >= 0x10000 {
+			n += 2
+		} else {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,114 @@
+package poster
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name, mode string
+		max        int
+		text       string
+		want       []string
+	}{
+		{"fits", "MarkdownV2", 20, `*short* \.`, []string{`*short* \.`}},
+		{"paragraphs first", "", 20, "one two three\n\nfour five six", []string{"one two three", "four five six"}},
+		{"lines before words", "", 16, "alpha beta\ngamma delta", []string{"alpha beta", "gamma delta"}},
+
+		// MarkdownV2
+		{"md escape kept whole", "MarkdownV2", 12, `one two three\. four five six\. seven`,
+			[]string{"one two", `three\. four`, `five six\.`, "seven"}},
+		{"md entity closed and reopened", "MarkdownV2", 12, "*bold words here and more* tail",
+			[]string{"*bold words*", "*here and*", "*more* tail"}},
+		{"md cut outside the entity", "MarkdownV2", 17, "lead words *bold* and tail",
+			[]string{"lead words *bold*", "and tail"}},
+		{"md link kept whole", "MarkdownV2", 30, "some text [the link](https://k8s.io/x) end",
+			[]string{"some text", "[the link](https://k8s.io/x)", "end"}},
+		{"md long link reopened", "MarkdownV2", 40, `[*Kubernetes v1\.31 is out*](https://k8s.io/a_(b\))`,
+			[]string{`[*Kubernetes*](https://k8s.io/a_(b\))`, `[*v1\.31 is out*](https://k8s.io/a_(b\))`}},
+		{"md code", "MarkdownV2", 24, "```go\nfmt.Println(1)\nfmt.Println(2)\n```",
+			[]string{"```go\nfmt.Println(1)```", "```go\nfmt.Println(2)\n```"}},
+
+		// HTML
+		{"html entity and tag kept whole", "HTML", 20, "<b>bold words &amp; here and more</b> tail",
+			[]string{"<b>bold words</b>", "<b>&amp; here</b>", "<b>and more</b> tail"}},
+		{"html link reopened", "HTML", 40, `<a href="https://x.io">a long link label here</a>`,
+			[]string{`<a href="https://x.io">a long link</a>`, `<a href="https://x.io">label here</a>`}},
+
+		// Telegram counts UTF-16 code units: each rocket is two.
+		{"utf16", "", 5, "🚀🚀🚀🚀 🚀🚀", []string{"🚀🚀", "🚀🚀", "🚀🚀"}},
+		{"utf16 words", "MarkdownV2", 9, "🚀🚀 🚀🚀 🚀🚀", []string{"🚀🚀 🚀🚀", "🚀🚀"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Split(tt.text, tt.mode, tt.max)
+			if strings.Join(got, "\x00") != strings.Join(tt.want, "\x00") {
+				t.Errorf("Split(%q, %d):\n got %q\nwant %q", tt.text, tt.max, got, tt.want)
+			}
+			checkParts(t, got, tt.mode, tt.max)
+		})
+	}
+}
+
+// TestSplitRendered splits a long rendered item in every parse mode and
+// checks that each part stands on its own.
+func TestSplitRendered(t *testing.T) {
+	it := SampleItem
+	it.Title = strings.Repeat("Cloud-native (v1.31) & <more> ", 20)
+	it.Summary = strings.Repeat("Kubernetes v1.31 brings 45 enhancements; 11 graduate to *stable* (GA). 🚀 ", 100)
+	for _, mode := range []string{"MarkdownV2", "HTML", ""} {
+		t.Run(mode, func(t *testing.T) {
+			text, err := (&TG{ParseMode: mode}).template().Execute(it)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parts := Split(text, mode, 300)
+			if len(parts) < 2 {
+				t.Fatalf("%d parts, want several", len(parts))
+			}
+			checkParts(t, parts, mode, 300)
+			if mode == "" && strings.Join(strings.Fields(strings.Join(parts, " ")), " ") != strings.Join(strings.Fields(text), " ") {
+				t.Errorf("plain parts do not add up to the text")
+			}
+		})
+	}
+}
+
+// checkParts fails t if a part is over max UTF-16 code units, leaves an
+// entity open or closes one it did not open, or ends inside an escape, a
+// tag or a character reference.
+func checkParts(t *testing.T, parts []string, mode string, max int) {
+	t.Helper()
+	for i, p := range parts {
+		if n := utf16Len(p); n > max {
+			t.Errorf("part %d is %d UTF-16 units, want at most %d: %q", i, n, max, p)
+		}
+		if strings.TrimSpace(p) == "" {
+			t.Errorf("part %d is empty", i)
+		}
+		var stack []frame
+		for _, u := range tokenize(p, mode) {
+			if u.pop && len(stack) == 0 {
+				t.Errorf("part %d closes %q that it never opened: %q", i, u.s, p)
+			}
+			stack = u.apply(stack)
+		}
+		if len(stack) > 0 {
+			t.Errorf("part %d leaves %q open: %q", i, openers(stack), p)
+		}
+		switch mode {
+		case "MarkdownV2":
+			if n := len(p) - len(strings.TrimRight(p, `\`)); n%2 == 1 {
+				t.Errorf("part %d ends in a lone backslash: %q", i, p)
+			}
+		case "HTML":
+			if j := strings.LastIndexByte(p, '<'); j > strings.LastIndexByte(p, '>') {
+				t.Errorf("part %d ends inside a tag: %q", i, p)
+			}
+			if j := strings.LastIndexByte(p, '&'); j >= 0 && !strings.Contains(p[j:], ";") {
+				t.Errorf("part %d ends inside a character reference: %q", i, p)
+			}
+		}
+	}
+}
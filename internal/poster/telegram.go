@@ -2,48 +2,285 @@ package poster
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
 	"strconv"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/LibenHailu/cncg-bot/internal/store"
-	"github.com/LibenHailu/cncg-bot/internal/util"
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// Defaults for TG's retry and pacing knobs. Telegram allows about 20
+// messages a minute to one group or channel.
+const (
+	defaultTGInterval = 3 * time.Second
+	defaultTGAttempts = 4
+	defaultTGBackoff  = 2 * time.Second
+)
+
 type TG struct {
 	Bot       *tgbotapi.BotAPI
-	ChannelID string
-	ParseMode string // "MarkdownV2"
+	ChannelID int64
+	ParseMode string    // "MarkdownV2"
+	Dest      string    // destination name; "telegram" if empty
+	Template  *Template // message layout; DefaultTemplate(ParseMode) if nil
+
+	Interval    time.Duration // minimum gap between sends to ChannelID; defaultTGInterval if 0
+	MaxAttempts int           // defaultTGAttempts if 0
+	Backoff     time.Duration // first retry delay for transient errors, doubled each time
 }
 
+// pace spaces sends per chat across every TG in the process, so two
+// destinations sharing a channel do not add up to a flood.
+var pace = struct {
+	sync.Mutex
+	next map[int64]time.Time
+}{next: map[int64]time.Time{}}
+
 func New(botToken, channelID, parseMode string) (*TG, error) {
+	chatID, err := strconv.ParseInt(channelID, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("channel id %q: %w", channelID, err)
+	}
 	bot, err := tgbotapi.NewBotAPI(botToken)
 	if err != nil {
 		return nil, err
 	}
-	return &TG{Bot: bot, ChannelID: channelID, ParseMode: parseMode}, nil
+	return &TG{Bot: bot, ChannelID: chatID, ParseMode: parseMode}, nil
 }
 
-func (t *TG) PostItem(ctx context.Context, it store.Item) error {
-	title := util.EscapeTelegram(it.Title)
-	url := util.EscapeTelegram(it.URL)
-	source := util.EscapeTelegram(it.Source)
-	sum := util.EscapeTelegram(it.Summary)
+func (t *TG) Name() string {
+	if t.Dest == "" {
+		return "telegram"
+	}
+	return t.Dest
+}
+
+// captionMax is Telegram's limit on photo captions.
+const captionMax = 1024
 
-	// Title as a clickable link, then 2–3 sentence summary + source attribution + tags
-	text := fmt.Sprintf("[*%s*](%s)\n\n%s\n\n_Source:_ %s", title, url, sum, source)
+// Publish sends it to the channel: as a photo with a caption when it has
+// an image, as a text message otherwise or when Telegram rejects the
+// image, split into a reply chain when it is longer than Telegram allows.
+// Flood-control answers (429) are retried after the retry_after Telegram
+// asks for and transient failures (5xx, network) with exponential
+// backoff. Errors about the channel itself (bot removed, chat gone) wrap
+// ErrDestination, other errors retrying cannot fix wrap ErrPermanent.
+func (t *TG) Publish(ctx context.Context, it store.Item) (Receipt, error) {
+	if it.Image != "" {
+		caption, err := Caption(it, t.template(), t.ParseMode)
+		if err != nil {
+			return Receipt{}, err
+		}
+		photo := tgbotapi.NewPhoto(t.ChannelID, tgbotapi.FileURL(it.Image))
+		photo.Caption = caption
+		photo.ParseMode = t.ParseMode
+		sent, err := t.send(ctx, photo)
+		if err == nil {
+			return Receipt{RemoteID: strconv.Itoa(sent.MessageID), At: sent.Time()}, nil
+		}
+		if !errors.Is(err, ErrPermanent) {
+			return Receipt{}, err
+		}
+		// Typically an image Telegram cannot fetch or decode.
+	}
 
-	channelId, err := strconv.Atoi(os.Getenv("CHANNEL_ID"))
+	text, err := t.template().Execute(it)
 	if err != nil {
-		return err
+		return Receipt{}, err
+	}
+	return t.sendText(ctx, Split(text, t.ParseMode, textMax), Receipt{})
+}
+
+// Resume sends the parts of it's reply chain that partial, the receipt of
+// a failed Publish, does not list, the first one replying to the last that
+// went out. Without a RemoteID in partial nothing went out and it is
+// published afresh.
+func (t *TG) Resume(ctx context.Context, it store.Item, partial Receipt) (Receipt, error) {
+	if partial.RemoteID == "" {
+		return t.Publish(ctx, it)
+	}
+	text, err := t.template().Execute(it)
+	if err != nil {
+		return partial, err
+	}
+	return t.sendText(ctx, Split(text, t.ParseMode, textMax), partial)
+}
+
+// sendText sends parts as a reply chain, each part answering the one
+// before, skipping those rc already lists. If a part fails, the receipt
+// still holds the ids of the parts that went out.
+func (t *TG) sendText(ctx context.Context, parts []string, rc Receipt) (Receipt, error) {
+	from, replyTo := 0, 0
+	if rc.RemoteID != "" {
+		ids := append([]string{rc.RemoteID}, rc.Replies...)
+		from = len(ids)
+		var err error
+		if replyTo, err = strconv.Atoi(ids[len(ids)-1]); err != nil {
+			return rc, fmt.Errorf("%w: message id %q: %v", ErrPermanent, ids[len(ids)-1], err)
+		}
+	}
+	for i := from; i < len(parts); i++ {
+		msg := tgbotapi.MessageConfig{
+			BaseChat:              tgbotapi.BaseChat{ChatID: t.ChannelID, ReplyToMessageID: replyTo},
+			Text:                  parts[i],
+			ParseMode:             t.ParseMode,
+			DisableWebPagePreview: i > 0, // one preview, for the item's link
+		}
+		sent, err := t.send(ctx, msg)
+		if err != nil {
+			if i > 0 {
+				err = fmt.Errorf("part %d of %d: %w", i+1, len(parts), err)
+			}
+			return rc, err
+		}
+		id := strconv.Itoa(sent.MessageID)
+		if i == 0 {
+			rc.RemoteID, rc.At = id, sent.Time()
+		} else {
+			rc.Replies = append(rc.Replies, id)
+		}
+		replyTo = sent.MessageID
+	}
+	return rc, nil
+}
+
+func (t *TG) template() *Template {
+	if t.Template == nil {
+		return DefaultTemplate(t.ParseMode)
+	}
+	return t.Template
+}
+
+// Caption renders it with tmpl, shortening the summary so the text fits a
+// photo caption. Like Telegram, it counts UTF-16 code units.
+func Caption(it store.Item, tmpl *Template, parseMode string) (string, error) {
+	text, err := fit(it, captionMax, utf16Len, tmpl.Execute)
+	if err != nil {
+		return "", err
+	}
+	if utf16Len(text) > captionMax {
+		// Only an absurdly long title gets here. Keep what fits without
+		// breaking the markup.
+		text = Split(text, parseMode, captionMax)[0]
+	}
+	return text, nil
+}
+
+// send delivers c with pacing and retries.
+func (t *TG) send(ctx context.Context, c tgbotapi.Chattable) (tgbotapi.Message, error) {
+	attempts := t.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultTGAttempts
+	}
+	backoff := t.Backoff
+	if backoff <= 0 {
+		backoff = defaultTGBackoff
+	}
+
+	for n := 1; ; n++ {
+		if err := t.wait(ctx); err != nil {
+			return tgbotapi.Message{}, err
+		}
+		sent, err := t.Bot.Send(c)
+		if err == nil {
+			return sent, nil
+		}
+		delay, retry := classifyTG(err)
+		if !retry {
+			return tgbotapi.Message{}, tgFailure(err)
+		}
+		if n == attempts {
+			return tgbotapi.Message{}, fmt.Errorf("after %d attempts: %w", n, err)
+		}
+		if delay == 0 {
+			delay, backoff = backoff, backoff*2
+		}
+		if err := sleep(ctx, delay); err != nil {
+			return tgbotapi.Message{}, err
+		}
+	}
+}
+
+// wait blocks until ChannelID may be sent to again and reserves the slot.
+func (t *TG) wait(ctx context.Context) error {
+	interval := t.Interval
+	if interval <= 0 {
+		interval = defaultTGInterval
+	}
+	pace.Lock()
+	now := time.Now()
+	at := pace.next[t.ChannelID]
+	if at.Before(now) {
+		at = now
+	}
+	pace.next[t.ChannelID] = at.Add(interval)
+	pace.Unlock()
+	return sleep(ctx, at.Sub(now))
+}
+
+// classifyTG reports whether err is worth retrying and, for flood control,
+// the retry_after Telegram asked for (0 means back off as usual). Other API
+// errors are final, see tgFailure.
+func classifyTG(err error) (retryAfter time.Duration, retry bool) {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return 0, true // network or decoding failure
+	}
+	switch {
+	case apiErr.RetryAfter > 0:
+		return time.Duration(apiErr.RetryAfter) * time.Second, true
+	case apiErr.Code == 429 || apiErr.Code >= 500:
+		return 0, true
+	}
+	return 0, false
+}
+
+// tgChatErrors are the Bad Request descriptions Telegram gives when the
+// chat, not the message, is the problem.
+var tgChatErrors = []string{
+	"chat not found",
+	"not enough rights",
+	"need administrator rights",
+	"have no rights",
+	"chat_write_forbidden",
+}
+
+// tgFailure wraps an API error that is not worth retrying: ErrDestination
+// when the bot cannot post to the chat at all (401 bad token, 403 kicked or
+// blocked, chat not found or missing rights), ErrPermanent otherwise.
+// Uploads do not carry the error code, so the description is checked too.
+func tgFailure(err error) error {
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) {
+		msg := strings.ToLower(apiErr.Message)
+		switch {
+		case apiErr.Code == 401 || apiErr.Code == 403 || apiErr.Code == 404,
+			strings.HasPrefix(msg, "unauthorized"), strings.HasPrefix(msg, "forbidden"):
+			return fmt.Errorf("%w: %v", ErrDestination, err)
+		}
+		for _, s := range tgChatErrors {
+			if strings.Contains(msg, s) {
+				return fmt.Errorf("%w: %v", ErrDestination, err)
+			}
+		}
+	}
+	return fmt.Errorf("%w: %v", ErrPermanent, err)
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
 	}
-	msg := tgbotapi.MessageConfig{
-		BaseChat:              tgbotapi.BaseChat{ChatID: int64(channelId)},
-		Text:                  text,
-		ParseMode:             t.ParseMode,
-		DisableWebPagePreview: false,
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
 	}
-	_, err = t.Bot.Send(msg)
-	return err
 }
@@ -0,0 +1,222 @@
+package poster
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/LibenHailu/cncg-bot/internal/store"
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// tgAnswer is a Bot API reply: a message id on success, an error code and
+// description otherwise, with the seconds to wait for flood control.
+type tgAnswer struct {
+	ID         int
+	Code       int
+	Desc       string
+	RetryAfter int
+}
+
+// tgStub returns a TG talking to a local Bot API that hands every send to
+// answer, along with the method name and form values.
+func tgStub(t *testing.T, answer func(method string, form url.Values) tgAnswer) *TG {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+		a := tgAnswer{ID: 1}
+		if method != "getMe" {
+			r.ParseForm()
+			a = answer(method, r.PostForm)
+		}
+		resp := map[string]any{"ok": true, "result": map[string]any{
+			"message_id": a.ID, "date": 0, "chat": map[string]any{"id": 1}, "id": 1, "is_bot": true,
+		}}
+		if a.Code != 0 {
+			resp = map[string]any{"ok": false, "error_code": a.Code, "description": a.Desc}
+			if a.RetryAfter > 0 {
+				resp["parameters"] = map[string]any{"retry_after": a.RetryAfter}
+			}
+			w.WriteHeader(a.Code)
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(srv.Close)
+
+	bot, err := tgbotapi.NewBotAPIWithClient("token", srv.URL+"/bot%s/%s", srv.Client())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &TG{Bot: bot, ChannelID: -100, Interval: time.Millisecond, Backoff: time.Millisecond}
+}
+
+func TestTGFailures(t *testing.T) {
+	tests := []struct {
+		name        string
+		answer      tgAnswer
+		destination bool
+		permanent   bool
+	}{
+		{"kicked", tgAnswer{Code: 403, Desc: "Forbidden: bot was kicked from the channel chat"}, true, false},
+		{"bad token", tgAnswer{Code: 401, Desc: "Unauthorized"}, true, false},
+		{"chat not found", tgAnswer{Code: 400, Desc: "Bad Request: chat not found"}, true, false},
+		{"no rights", tgAnswer{Code: 400, Desc: "Bad Request: not enough rights to send text messages to the chat"}, true, false},
+		{"bad markup", tgAnswer{Code: 400, Desc: "Bad Request: can't parse entities: Character '.' is reserved"}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			tg := tgStub(t, func(string, url.Values) tgAnswer {
+				calls++
+				return tt.answer
+			})
+			_, err := tg.Publish(context.Background(), store.Item{Title: "t", URL: "https://example.com"})
+			if got := errors.Is(err, ErrDestination); got != tt.destination {
+				t.Errorf("destination = %v, want %v (%v)", got, tt.destination, err)
+			}
+			if got := errors.Is(err, ErrPermanent); got != tt.permanent {
+				t.Errorf("permanent = %v, want %v (%v)", got, tt.permanent, err)
+			}
+			if calls != 1 {
+				t.Errorf("%d sends, want 1: the error is not worth retrying", calls)
+			}
+		})
+	}
+}
+
+// TestTGRetry fails the first send with a transient error: the message
+// goes out once, on the second attempt.
+func TestTGRetry(t *testing.T) {
+	tests := []struct {
+		name     string
+		answer   tgAnswer
+		minDelay time.Duration
+	}{
+		{"flood control", tgAnswer{Code: 429, Desc: "Too Many Requests: retry after 1", RetryAfter: 1}, time.Second},
+		{"server error", tgAnswer{Code: 500, Desc: "Internal Server Error"}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sent []time.Time
+			tg := tgStub(t, func(string, url.Values) tgAnswer {
+				sent = append(sent, time.Now())
+				if len(sent) == 1 {
+					return tt.answer
+				}
+				return tgAnswer{ID: 42}
+			})
+			rc, err := tg.Publish(context.Background(), store.Item{Title: "t", URL: "https://example.com"})
+			if err != nil {
+				t.Fatalf("Publish = %v", err)
+			}
+			if len(sent) != 2 || rc.RemoteID != "42" {
+				t.Fatalf("%d sends, receipt %+v; want the message sent once after one retry", len(sent), rc)
+			}
+			if wait := sent[1].Sub(sent[0]); wait < tt.minDelay {
+				t.Errorf("retried after %v, want at least %v", wait, tt.minDelay)
+			}
+		})
+	}
+}
+
+// TestTGPhotoFallback checks that a rejected image falls back to text but
+// a chat the bot cannot post to does not get a second try.
+func TestTGPhotoFallback(t *testing.T) {
+	it := store.Item{Title: "t", URL: "https://example.com", Image: "https://example.com/i.png"}
+
+	var methods []string
+	tg := tgStub(t, func(method string, _ url.Values) tgAnswer {
+		methods = append(methods, method)
+		if method == "sendPhoto" {
+			return tgAnswer{Code: 400, Desc: "Bad Request: wrong file identifier/HTTP URL specified"}
+		}
+		return tgAnswer{ID: 7}
+	})
+	rc, err := tg.Publish(context.Background(), it)
+	if err != nil || rc.RemoteID != "7" || strings.Join(methods, ",") != "sendPhoto,sendMessage" {
+		t.Errorf("Publish = %+v, %v after %v; want the text message", rc, err, methods)
+	}
+
+	methods = nil
+	tg = tgStub(t, func(method string, _ url.Values) tgAnswer {
+		methods = append(methods, method)
+		return tgAnswer{Code: 403, Desc: "Forbidden: bot is not a member of the channel chat"}
+	})
+	if _, err := tg.Publish(context.Background(), it); !errors.Is(err, ErrDestination) || len(methods) != 1 {
+		t.Errorf("Publish = %v after %v; want ErrDestination after one send", err, methods)
+	}
+}
+
+func TestCaptionUTF16(t *testing.T) {
+	// 700 emoji are 700 runes but 1400 UTF-16 code units.
+	emoji := strings.Repeat("🚀", 700)
+	for _, mode := range []string{"MarkdownV2", "HTML", ""} {
+		t.Run("summary "+mode, func(t *testing.T) {
+			it := store.Item{Title: "Launch", URL: "https://example.com", Summary: "go " + emoji}
+			got, err := Caption(it, DefaultTemplate(mode), mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := utf16Len(got); n > captionMax {
+				t.Errorf("caption is %d UTF-16 units, want at most %d", n, captionMax)
+			}
+			if !strings.Contains(got, "Launch") {
+				t.Errorf("caption lost the title:\n%s", got)
+			}
+		})
+		t.Run("title "+mode, func(t *testing.T) {
+			it := store.Item{Title: strings.Repeat("a.b ", 200) + emoji, URL: "https://example.com"}
+			got, err := Caption(it, DefaultTemplate(mode), mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkParts(t, []string{got}, mode, captionMax)
+		})
+	}
+}
+
+// TestTGResume breaks a three-part reply chain at the last part, then
+// resumes it: only the missing part goes out, answering the second.
+func TestTGResume(t *testing.T) {
+	it := store.Item{Title: "Long read", URL: "https://example.com", Summary: strings.Repeat("word ", 2000)}
+	type send struct{ replyTo, preview string }
+	var sends []send
+	fail, id := true, 10
+	tg := tgStub(t, func(method string, form url.Values) tgAnswer {
+		sends = append(sends, send{form.Get("reply_to_message_id"), form.Get("disable_web_page_preview")})
+		if len(sends) == 3 && fail {
+			return tgAnswer{Code: 500, Desc: "Internal Server Error"}
+		}
+		id++
+		return tgAnswer{ID: id}
+	})
+	tg.MaxAttempts = 1
+
+	rc, err := tg.Publish(context.Background(), it)
+	if err == nil || rc.RemoteID != "11" || strings.Join(rc.Replies, ",") != "12" {
+		t.Fatalf("Publish = %+v, %v; want the first two parts and an error", rc, err)
+	}
+
+	sends, fail = nil, false
+	rc, err = tg.Resume(context.Background(), it, rc)
+	if err != nil {
+		t.Fatalf("Resume = %v", err)
+	}
+	if len(sends) != 1 || sends[0].replyTo != "12" || sends[0].preview != "true" {
+		t.Errorf("Resume sent %+v, want one part replying to 12 without a preview", sends)
+	}
+	if rc.RemoteID != "11" || strings.Join(rc.Replies, ",") != "12,13" {
+		t.Errorf("receipt = %+v, want the whole chain", rc)
+	}
+
+	sends = nil
+	if _, err := tg.Resume(context.Background(), it, Receipt{}); err != nil || len(sends) != 3 {
+		t.Errorf("Resume without a RemoteID = %v after %d sends, want a full publish", err, len(sends))
+	}
+}
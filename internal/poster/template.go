@@ -0,0 +1,161 @@
+package poster
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+// DefaultTelegramTemplate is the layout TG uses unless its destination
+// sets one: the title as a link, the summary, the source and the tags. It
+// only formats through the helpers, so it works in every parse mode.
+const DefaultTelegramTemplate = `{{link (bold (esc .Title)) .URL}}
+
+{{esc .Summary}}
+
+{{italic "Source:"}} {{esc .Source}}{{with hashtags .Tags}}
+{{esc .}}{{end}}`
+
+// defaultTelegram holds DefaultTelegramTemplate parsed for each parse mode.
+var defaultTelegram = map[string]*Template{
+	"MarkdownV2": MustTemplate(DefaultTelegramTemplate, MarkdownV2),
+	"HTML":       MustTemplate(DefaultTelegramTemplate, HTML),
+	"":           MustTemplate(DefaultTelegramTemplate, Plain),
+}
+
+// DefaultTemplate returns DefaultTelegramTemplate for parseMode, falling
+// back to MarkdownV2 for modes it does not know.
+func DefaultTemplate(parseMode string) *Template {
+	if t, ok := defaultTelegram[parseMode]; ok {
+		return t
+	}
+	return defaultTelegram["MarkdownV2"]
+}
+
+// Template renders a store.Item to message text. Besides the item fields
+// ({{.Title}}, {{.URL}}, {{.Summary}}, {{.Source}}, {{.Tags}},
+// {{.PublishedAt}}, {{.Score}}, {{.Image}}) templates can use:
+//
+//	esc s          escape s as text in the destination's markup
+//	escURL u       escape u as a link target
+//	link label u   a link to u; label must already be escaped
+//	bold s         bold text; s must already be escaped
+//	italic s       italic text; s must already be escaped
+//	hashtags tags  "#k8s #CloudNative" from the comma-separated tags
+//	tags tags      the tags as a list, for {{range}}
+//	ago t          "3 hours ago", "yesterday", "2 weeks ago"
+//	date layout t  t formatted with a Go time layout
+//	truncate n s   s cut to n characters at a word boundary
+type Template struct {
+	t *template.Template
+}
+
+// ParseTemplate parses text with the helpers above, formatting with m.
+func ParseTemplate(text string, m Markup) (*Template, error) {
+	t, err := template.New("message").Funcs(template.FuncMap{
+		"esc":      m.Escape,
+		"escURL":   m.EscapeURL,
+		"link":     m.Link,
+		"bold":     m.Bold,
+		"italic":   m.Italic,
+		"hashtags": func(tags string) string { return strings.Join(Hashtags(tags), " ") },
+		"tags":     splitTags,
+		"ago":      func(t time.Time) string { return ago(t, time.Now()) },
+		"date":     func(layout string, t time.Time) string { return t.Format(layout) },
+		"truncate": func(n int, s string) string { return truncateWords(s, n) },
+	}).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	return &Template{t: t}, nil
+}
+
+// MustTemplate is ParseTemplate for templates known to be valid.
+func MustTemplate(text string, m Markup) *Template {
+	t, err := ParseTemplate(text, m)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Execute renders it.
+func (t *Template) Execute(it store.Item) (string, error) {
+	var b strings.Builder
+	if err := t.t.Execute(&b, it); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// SampleItem is what templates are test-rendered with at startup.
+var SampleItem = store.Item{
+	ID:          1,
+	Source:      "Kubernetes Blog",
+	Title:       "Kubernetes v1.31: Elli",
+	URL:         "https://kubernetes.io/blog/2024/08/13/kubernetes-v1-31-release/",
+	Summary:     "Kubernetes v1.31 brings 45 enhancements. 11 of them graduate to stable (GA).",
+	PublishedAt: time.Date(2024, 8, 13, 0, 0, 0, 0, time.UTC),
+	Tags:        "kubernetes,release,cloud native",
+	Score:       0.92,
+	Image:       "https://kubernetes.io/images/blog/kubernetes-v1-31.png",
+}
+
+// fit renders it and, while the result is longer than max as measured by
+// length, cuts the summary shorter at a word boundary and renders again.
+// It is left to the caller to deal with text that is still too long once
+// the summary is gone.
+func fit(it store.Item, max int, length func(string) int, render func(store.Item) (string, error)) (string, error) {
+	text, err := render(it)
+	for err == nil && length(text) > max && it.Summary != "" {
+		n := len([]rune(it.Summary)) - (length(text) - max)
+		if n < 20 {
+			it.Summary = ""
+		} else {
+			it.Summary = truncateWords(it.Summary, n)
+		}
+		text, err = render(it)
+	}
+	return text, err
+}
+
+func runeLen(s string) int { return len([]rune(s)) }
+
+func splitTags(tags string) []string {
+	var out []string
+	for _, t := range strings.Split(tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// ago describes how long before now t was, coarsely.
+func ago(t, now time.Time) string {
+	d := now.Sub(t)
+	plural := func(n int, unit string) string {
+		if n == 1 {
+			return "1 " + unit + " ago"
+		}
+		return fmt.Sprintf("%d %ss ago", n, unit)
+	}
+	switch {
+	case d < time.Minute:
+		return "just now"
+	case d < time.Hour:
+		return plural(int(d/time.Minute), "minute")
+	case d < 24*time.Hour:
+		return plural(int(d/time.Hour), "hour")
+	case d < 48*time.Hour:
+		return "yesterday"
+	case d < 14*24*time.Hour:
+		return plural(int(d/(24*time.Hour)), "day")
+	case d < 60*24*time.Hour:
+		return plural(int(d/(7*24*time.Hour)), "week")
+	}
+	return t.Format("2 Jan 2006")
+}
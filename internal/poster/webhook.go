@@ -0,0 +1,162 @@
+package poster
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+// Headers set on every webhook delivery. The signature is
+// "sha256=" + hex(HMAC-SHA256(secret, body)).
+const (
+	SignatureHeader = "X-CNCG-Signature"
+	DeliveryHeader  = "X-CNCG-Delivery"
+)
+
+const (
+	defaultWebhookAttempts = 5
+	defaultWebhookBackoff  = time.Second
+)
+
+// Webhook POSTs items as signed JSON to an arbitrary endpoint. Network
+// errors, 429 and 5xx responses are retried with exponential backoff; when
+// the attempts run out, or the endpoint rejects the payload, Publish returns
+// an *UndeliverableError carrying it for the dead-letter record. An endpoint
+// that refuses every delivery (401, 403, 404, 410) gets an ErrDestination
+// instead: the item is not at fault.
+type Webhook struct {
+	URL         string
+	Secret      string
+	MaxAttempts int           // defaultWebhookAttempts if <= 0
+	Backoff     time.Duration // first retry delay, doubled each time; defaultWebhookBackoff if <= 0
+	Client      *http.Client
+	Dest        string // destination name; "webhook" if empty
+}
+
+// WebhookPayload is the JSON body of a delivery.
+type WebhookPayload struct {
+	ID          int64     `json:"id"`
+	Source      string    `json:"source"`
+	Title       string    `json:"title"`
+	URL         string    `json:"url"`
+	Summary     string    `json:"summary"`
+	PublishedAt time.Time `json:"published_at"`
+	Tags        []string  `json:"tags"`
+	Score       float64   `json:"score"`
+}
+
+// UndeliverableError reports a delivery that failed for good, with the
+// payload that could not be delivered.
+type UndeliverableError struct {
+	Payload  []byte
+	Attempts int
+	Err      error
+}
+
+func (e *UndeliverableError) Error() string {
+	return fmt.Sprintf("undeliverable after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *UndeliverableError) Unwrap() error { return e.Err }
+
+func (w *Webhook) Name() string {
+	if w.Dest == "" {
+		return "webhook"
+	}
+	return w.Dest
+}
+
+func (w *Webhook) Publish(ctx context.Context, it store.Item) (Receipt, error) {
+	body, err := json.Marshal(newWebhookPayload(it))
+	if err != nil {
+		return Receipt{}, err
+	}
+	attempts := w.MaxAttempts
+	if attempts <= 0 {
+		attempts = defaultWebhookAttempts
+	}
+	delay := w.Backoff
+	if delay <= 0 {
+		delay = defaultWebhookBackoff
+	}
+
+	var lastErr error
+	n := 0
+	for n < attempts {
+		n++
+		if lastErr = w.send(ctx, it.Hash, body); lastErr == nil {
+			return Receipt{}, nil
+		}
+		if errors.Is(lastErr, ErrDestination) {
+			return Receipt{}, lastErr
+		}
+		if errors.Is(lastErr, ErrPermanent) || n == attempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return Receipt{}, ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+	return Receipt{}, &UndeliverableError{Payload: body, Attempts: n, Err: lastErr}
+}
+
+func (w *Webhook) send(ctx context.Context, delivery string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPermanent, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(SignatureHeader, Sign(w.Secret, body))
+	if delivery != "" {
+		req.Header.Set(DeliveryHeader, delivery)
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return statusError("webhook", resp, msg)
+}
+
+// Sign returns the SignatureHeader value for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func newWebhookPayload(it store.Item) WebhookPayload {
+	p := WebhookPayload{
+		ID: it.ID, Source: it.Source, Title: it.Title, URL: it.URL, Summary: it.Summary,
+		PublishedAt: it.PublishedAt.UTC(), Tags: []string{}, Score: it.Score,
+	}
+	for _, t := range strings.Split(it.Tags, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			p.Tags = append(p.Tags, t)
+		}
+	}
+	return p
+}
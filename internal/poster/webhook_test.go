@@ -0,0 +1,86 @@
+package poster
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/LibenHailu/cncg-bot/internal/store"
+)
+
+func TestSign(t *testing.T) {
+	// HMAC-SHA256 reference value.
+	got := Sign("key", []byte("The quick brown fox jumps over the lazy dog"))
+	want := "sha256=f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"
+	if got != want {
+		t.Errorf("Sign = %s, want %s", got, want)
+	}
+}
+
+func TestWebhookDeliverySigned(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		if got := r.Header.Get(SignatureHeader); got != Sign("s3cret", body) {
+			t.Errorf("signature %q does not match the body", got)
+		}
+		if got := r.Header.Get(DeliveryHeader); got != "hash-1" {
+			t.Errorf("%s = %q, want the item hash", DeliveryHeader, got)
+		}
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	wh := &Webhook{URL: srv.URL, Secret: "s3cret", Backoff: time.Millisecond}
+	if _, err := wh.Publish(context.Background(), store.Item{ID: 1, Title: "t", Hash: "hash-1"}); err != nil {
+		t.Fatalf("Publish = %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("%d calls, want a retry after the 503", calls)
+	}
+}
+
+func TestWebhookClientErrorIsPermanent(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "unknown field", http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	wh := &Webhook{URL: srv.URL, Secret: "s3cret", Backoff: time.Millisecond}
+	_, err := wh.Publish(context.Background(), store.Item{ID: 1, Title: "t"})
+	var ue *UndeliverableError
+	if !errors.As(err, &ue) || !errors.Is(err, ErrPermanent) {
+		t.Fatalf("Publish = %v, want a permanent *UndeliverableError", err)
+	}
+	if calls != 1 || ue.Attempts != 1 || len(ue.Payload) == 0 {
+		t.Errorf("calls=%d attempts=%d payload=%q, want one attempt with the payload kept", calls, ue.Attempts, ue.Payload)
+	}
+}
+
+func TestWebhookEndpointDown(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "bad signature", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	wh := &Webhook{URL: srv.URL, Secret: "wrong", Backoff: time.Millisecond}
+	_, err := wh.Publish(context.Background(), store.Item{ID: 1, Title: "t"})
+	var ue *UndeliverableError
+	if !errors.Is(err, ErrDestination) || errors.As(err, &ue) {
+		t.Fatalf("Publish = %v, want ErrDestination and no dead letter", err)
+	}
+	if calls != 1 {
+		t.Errorf("%d calls, want 1", calls)
+	}
+}
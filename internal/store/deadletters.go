@@ -0,0 +1,56 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// DeadLetter is a delivery a destination gave up on, kept with the exact
+// payload so it can be inspected or replayed. The item's post for that
+// destination has status PostDead.
+type DeadLetter struct {
+	Destination string
+	ItemID      int64
+	Payload     string
+	Attempts    int
+	LastError   string
+	CreatedAt   time.Time
+}
+
+// AddDeadLetter records that delivering an item to d.Destination failed for
+// good. A second failure for the same pair replaces the first.
+func (s *Store) AddDeadLetter(ctx context.Context, d DeadLetter) error {
+	if d.CreatedAt.IsZero() {
+		d.CreatedAt = time.Now().UTC()
+	}
+	_, err := s.DB.ExecContext(ctx, `
+INSERT INTO dead_letters (destination,item_id,payload,attempts,last_error,created_at)
+VALUES ($1,$2,$3,$4,$5,$6)
+ON CONFLICT(destination,item_id) DO UPDATE SET
+    payload=excluded.payload, attempts=excluded.attempts,
+    last_error=excluded.last_error, created_at=excluded.created_at
+`, d.Destination, d.ItemID, d.Payload, d.Attempts, d.LastError, d.CreatedAt)
+	return err
+}
+
+// DeadLetters lists the dead letters of destination, newest first.
+func (s *Store) DeadLetters(ctx context.Context, destination string) ([]DeadLetter, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+SELECT destination,item_id,payload,attempts,last_error,created_at
+FROM dead_letters WHERE destination=$1 ORDER BY created_at DESC
+`, destination)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []DeadLetter
+	for rows.Next() {
+		var d DeadLetter
+		if err := rows.Scan(&d.Destination, &d.ItemID, &d.Payload, &d.Attempts, &d.LastError, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		out = append(out, d)
+	}
+	return out, rows.Err()
+}
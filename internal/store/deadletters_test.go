@@ -0,0 +1,40 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	db := openTestStore(t)
+	ids := addItems(t, db, "a", "b")
+	t0 := time.Now().UTC().Add(-time.Hour).Truncate(time.Second)
+	for _, d := range []DeadLetter{
+		{Destination: "hook", ItemID: ids["a"], Payload: `{"id":1}`, Attempts: 5, LastError: "503", CreatedAt: t0},
+		{Destination: "hook", ItemID: ids["b"], Payload: `{"id":2}`, Attempts: 5, LastError: "503", CreatedAt: t0.Add(time.Minute)},
+		{Destination: "other", ItemID: ids["a"], Payload: `{}`, Attempts: 1, LastError: "400"},
+		// A second failure replaces the first.
+		{Destination: "hook", ItemID: ids["a"], Payload: `{"id":1,"v":2}`, Attempts: 3, LastError: "502", CreatedAt: t0.Add(2 * time.Minute)},
+	} {
+		if err := db.AddDeadLetter(ctx, d); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := db.DeadLetters(ctx, "hook")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("%d dead letters, want 2: %+v", len(got), got)
+	}
+	if d := got[0]; d.ItemID != ids["a"] || d.Payload != `{"id":1,"v":2}` || d.Attempts != 3 || d.LastError != "502" ||
+		!d.CreatedAt.Equal(t0.Add(2*time.Minute)) {
+		t.Errorf("newest = %+v, want the replaced letter for a", d)
+	}
+	if got[1].ItemID != ids["b"] {
+		t.Errorf("oldest = %+v, want b", got[1])
+	}
+}
@@ -0,0 +1,84 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/LibenHailu/cncg-bot/internal/util"
+)
+
+// LinkNearDuplicate compares the item stored under hash with the canonical
+// items published since `since`. If one is within maxDist bits of SimHash
+// distance, the two are linked: the higher-scored item stays canonical and
+// the other (with any duplicates of its own) points at it through
+// canonical_id, so only one of them is ever returned by NextUnposted. An
+// item that was already posted always stays canonical.
+//
+// It returns the canonical id the item was linked to, or 0 if it is unique.
+func (s *Store) LinkNearDuplicate(ctx context.Context, hash string, maxDist int, since time.Time) (int64, error) {
+	tx, err := s.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	var (
+		id     int64
+		sh     int64
+		score  float64
+		posted bool
+	)
+	err = tx.QueryRowContext(ctx, `SELECT id,simhash,score,`+postedExpr+` FROM items WHERE hash=$1`, hash).
+		Scan(&id, &sh, &score, &posted)
+	if err != nil {
+		return 0, err
+	}
+	if sh == 0 {
+		return 0, nil
+	}
+
+	rows, err := tx.QueryContext(ctx, `
+SELECT id,simhash,score,`+postedExpr+`
+FROM items
+WHERE canonical_id IS NULL AND id<>$1 AND simhash<>0 AND published_at >= $2`, id, since.UTC())
+	if err != nil {
+		return 0, err
+	}
+	var (
+		best       int64
+		bestDist   = maxDist + 1
+		bestScore  float64
+		bestPosted bool
+	)
+	for rows.Next() {
+		var cid, csh int64
+		var cscore float64
+		var cposted bool
+		if err := rows.Scan(&cid, &csh, &cscore, &cposted); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		if d := util.HammingDistance(uint64(sh), uint64(csh)); d < bestDist {
+			best, bestDist, bestScore, bestPosted = cid, d, cscore, cposted
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if best == 0 {
+		return 0, nil
+	}
+
+	canon, dup := best, id
+	if !bestPosted && (posted || score > bestScore) {
+		canon, dup = id, best
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE items SET canonical_id=$1 WHERE id=$2 OR canonical_id=$2`, canon, dup); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return canon, nil
+}
@@ -0,0 +1,143 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/LibenHailu/cncg-bot/internal/util"
+)
+
+// Copies of one post as syndicated by different sources, and another post.
+var (
+	copyBlog   = Item{Title: "Kubernetes v1.31: Elli", Summary: "Kubernetes v1.31 is out with 45 enhancements. 11 have graduated to stable, 22 are entering beta and 12 are alpha."}
+	copyMedium = Item{Title: "Kubernetes v1.31: Elli", Summary: "Kubernetes v1.31 is out with 45 enhancements. Originally published on kubernetes.io."}
+	copyCNCF   = Item{Title: "Kubernetes v1.31: Elli", Summary: "Kubernetes v1.31 is out with 45 enhancements, 11 of them graduated to stable."}
+	otherPost  = Item{Title: "Prometheus 3.0 beta released", Summary: "The Prometheus team is proud to announce the beta of Prometheus 3.0, with a new UI."}
+)
+
+const testMaxDistance = 11
+
+// addDup stores it from source with score, runs LinkNearDuplicate on it and
+// returns its id and the canonical id it was linked to.
+func addDup(t *testing.T, db *Store, it Item, source string, score float64) (id, canon int64) {
+	t.Helper()
+	ctx := context.Background()
+	it.Source, it.URL, it.Score = source, "https://"+source+"/elli", score
+	it.PublishedAt = time.Now().UTC().Add(-time.Hour)
+	it.Hash = Hash(it.URL, it.Title)
+	it.SimHash = util.SimHash(it.Title, it.Summary)
+	if _, err := db.InsertIfNew(ctx, it); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DB.QueryRowContext(ctx, `SELECT id FROM items WHERE hash=$1`, it.Hash).Scan(&id); err != nil {
+		t.Fatal(err)
+	}
+	canon, err := db.LinkNearDuplicate(ctx, it.Hash, testMaxDistance, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id, canon
+}
+
+// canonicalOf returns the canonical_id of item id, 0 for a canonical item.
+func canonicalOf(t *testing.T, db *Store, id int64) int64 {
+	t.Helper()
+	var c sql.NullInt64
+	if err := db.DB.QueryRowContext(context.Background(), `SELECT canonical_id FROM items WHERE id=$1`, id).Scan(&c); err != nil {
+		t.Fatal(err)
+	}
+	return c.Int64
+}
+
+func TestLinkNearDuplicate(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("lower score joins", func(t *testing.T) {
+		db := openTestStore(t)
+		blog, _ := addDup(t, db, copyBlog, "kubernetes.io", 0.8)
+		medium, canon := addDup(t, db, copyMedium, "medium.com", 0.5)
+		if canon != blog || canonicalOf(t, db, medium) != blog || canonicalOf(t, db, blog) != 0 {
+			t.Errorf("linked to %d, want the blog post %d to stay canonical", canon, blog)
+		}
+		items, err := db.NextUnposted(ctx, []string{"telegram"}, 0, since, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(items) != 1 || items[0].ID != blog {
+			t.Errorf("NextUnposted = %v, want only the canonical item", titles(items))
+		}
+	})
+
+	t.Run("higher score takes over", func(t *testing.T) {
+		db := openTestStore(t)
+		medium, _ := addDup(t, db, copyMedium, "medium.com", 0.5)
+		blog, canon := addDup(t, db, copyBlog, "kubernetes.io", 0.8)
+		if canon != blog || canonicalOf(t, db, medium) != blog || canonicalOf(t, db, blog) != 0 {
+			t.Errorf("linked to %d, want the higher-scored %d canonical", canon, blog)
+		}
+	})
+
+	t.Run("posted stays canonical", func(t *testing.T) {
+		db := openTestStore(t)
+		medium, _ := addDup(t, db, copyMedium, "medium.com", 0.5)
+		if err := db.RecordPost(ctx, Post{ItemID: medium, Destination: "telegram", Status: PostSent}); err != nil {
+			t.Fatal(err)
+		}
+		blog, canon := addDup(t, db, copyBlog, "kubernetes.io", 0.8)
+		if canon != medium || canonicalOf(t, db, blog) != medium {
+			t.Errorf("linked to %d, want the posted %d canonical", canon, medium)
+		}
+	})
+
+	t.Run("duplicates follow", func(t *testing.T) {
+		db := openTestStore(t)
+		medium, _ := addDup(t, db, copyMedium, "medium.com", 0.5)
+		cncf, _ := addDup(t, db, copyCNCF, "cncf.io", 0.3)
+		if canonicalOf(t, db, cncf) != medium {
+			t.Fatalf("cncf.io copy not linked to %d", medium)
+		}
+		blog, canon := addDup(t, db, copyBlog, "kubernetes.io", 0.8)
+		if canon != blog || canonicalOf(t, db, medium) != blog || canonicalOf(t, db, cncf) != blog {
+			t.Errorf("canonical ids %d, %d; want both copies re-pointed at %d",
+				canonicalOf(t, db, medium), canonicalOf(t, db, cncf), blog)
+		}
+	})
+
+	t.Run("unique", func(t *testing.T) {
+		db := openTestStore(t)
+		addDup(t, db, copyBlog, "kubernetes.io", 0.8)
+		if id, canon := addDup(t, db, otherPost, "prometheus.io", 0.5); canon != 0 || canonicalOf(t, db, id) != 0 {
+			t.Errorf("different post linked to %d", canon)
+		}
+	})
+
+	t.Run("window", func(t *testing.T) {
+		db := openTestStore(t)
+		addDup(t, db, copyBlog, "kubernetes.io", 0.8)
+		id, _ := addDup(t, db, copyMedium, "medium.com", 0.5)
+		if _, err := db.DB.ExecContext(ctx, `UPDATE items SET canonical_id=NULL`); err != nil {
+			t.Fatal(err)
+		}
+		hash := Hash("https://medium.com/elli", copyMedium.Title)
+		// Published an hour ago: outside a window starting half an hour
+		// ago, inside one starting two hours ago even when given in a
+		// zone ahead of UTC.
+		for _, tt := range []struct {
+			since time.Time
+			want  bool
+		}{
+			{time.Now().Add(-30 * time.Minute), false},
+			{time.Now().Add(-2 * time.Hour).In(time.FixedZone("UTC+14", 14*3600)), true},
+		} {
+			canon, err := db.LinkNearDuplicate(ctx, hash, testMaxDistance, tt.since)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if linked := canon != 0 && canonicalOf(t, db, id) != 0; linked != tt.want {
+				t.Errorf("since %v: linked = %v, want %v", tt.since, linked, tt.want)
+			}
+		}
+	})
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseDSN(t *testing.T) {
+	tests := []struct {
+		dsn     string
+		dialect string
+		want    string
+	}{
+		{"postgres://bot:s3cret@db:5432/cncg?sslmode=disable", "postgres", "postgres://bot:s3cret@db:5432/cncg?sslmode=disable"},
+		{"postgresql://bot@db/cncg", "postgres", "postgresql://bot@db/cncg"},
+		{"host=db user=bot password=s3cret dbname=cncg sslmode=disable", "postgres", "host=db user=bot password=s3cret dbname=cncg sslmode=disable"},
+		{"  dbname = cncg", "postgres", "  dbname = cncg"},
+		{"file:data.db?_pragma=busy_timeout(5000)", "sqlite", "file:data.db?_pragma=busy_timeout(5000)"},
+		{"sqlite:data.db", "sqlite", "file:data.db"},
+		{"sqlite:///tmp/data.db", "sqlite", "file:/tmp/data.db"},
+		{"data/bot.db", "sqlite", "file:data/bot.db"},
+		{"/var/lib/cncg/bot.db", "sqlite", "file:/var/lib/cncg/bot.db"},
+	}
+	for _, tt := range tests {
+		d, dsn, err := parseDSN(tt.dsn)
+		if err != nil || d.Name != tt.dialect || dsn != tt.want {
+			t.Errorf("parseDSN(%q) = %s, %q, %v; want %s, %q", tt.dsn, d.Name, dsn, err, tt.dialect, tt.want)
+		}
+	}
+}
+
+func TestParseDSNErrorsHideSecrets(t *testing.T) {
+	tests := []struct {
+		dsn, want string
+	}{
+		{"mysql://bot:s3cret@db/cncg", `unsupported DSN scheme "mysql"`},
+		{"bot:s3cret@db://cncg", "unsupported DSN"},
+		{"", "empty DSN"},
+	}
+	for _, tt := range tests {
+		_, _, err := parseDSN(tt.dsn)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("parseDSN(%q) = %v, want %q", tt.dsn, err, tt.want)
+		}
+		if err != nil && strings.Contains(err.Error(), "s3cret") {
+			t.Errorf("parseDSN(%q) error leaks the password: %v", tt.dsn, err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+// FeedCache holds the HTTP validators last returned by a source URL.
+type FeedCache struct {
+	ETag         string
+	LastModified string
+}
+
+// FeedCaches returns the stored validators keyed by source URL.
+func (s *Store) FeedCaches(ctx context.Context) (map[string]FeedCache, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT url,etag,last_modified FROM feed_cache`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]FeedCache{}
+	for rows.Next() {
+		var url string
+		var fc FeedCache
+		if err := rows.Scan(&url, &fc.ETag, &fc.LastModified); err != nil {
+			return nil, err
+		}
+		out[url] = fc
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) SaveFeedCache(ctx context.Context, url string, fc FeedCache) error {
+	_, err := s.DB.ExecContext(ctx, `
+INSERT INTO feed_cache (url,etag,last_modified,updated_at)
+VALUES ($1,$2,$3,$4)
+ON CONFLICT(url) DO UPDATE SET etag=excluded.etag, last_modified=excluded.last_modified, updated_at=excluded.updated_at
+`, url, fc.ETag, fc.LastModified, time.Now().UTC())
+	return err
+}
@@ -0,0 +1,174 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Post statuses. An item is settled for a destination once its post there
+// is PostSent or PostDead; PostFailed posts are retried.
+const (
+	PostSent   = "sent"
+	PostFailed = "failed"
+	PostDead   = "dead" // given up on; webhooks keep the payload in dead_letters
+)
+
+// Post is the latest delivery attempt of an item to one destination.
+type Post struct {
+	ItemID      int64
+	Destination string
+	RemoteID    string   // e.g. the Telegram message id
+	Replies     []string // ids of the follow-up messages of a reply chain
+	Status      string
+	Error       string
+	PostedAt    time.Time
+}
+
+// postedExpr derives an item's posted flag from the posts table: it counts
+// as posted once any destination has it.
+const postedExpr = `EXISTS (SELECT 1 FROM posts WHERE posts.item_id=items.id AND posts.status='sent')`
+
+// postsIn matches the posts of items.id with one of statuses at one of the
+// destinations bound to marks.
+func postsIn(statuses, marks string) string {
+	return `EXISTS (SELECT 1 FROM posts WHERE posts.item_id=items.id AND posts.status IN (` + statuses + `) AND posts.destination IN (` + marks + `))`
+}
+
+// RecordPost stores the outcome of delivering p.ItemID to p.Destination,
+// replacing the previous attempt.
+func (s *Store) RecordPost(ctx context.Context, p Post) error {
+	if p.PostedAt.IsZero() {
+		p.PostedAt = time.Now()
+	}
+	_, err := s.DB.ExecContext(ctx, `
+INSERT INTO posts (item_id,destination,remote_id,reply_ids,status,error,posted_at)
+VALUES ($1,$2,$3,$4,$5,$6,$7)
+ON CONFLICT(item_id,destination) DO UPDATE SET
+    remote_id=excluded.remote_id, reply_ids=excluded.reply_ids, status=excluded.status,
+    error=excluded.error, posted_at=excluded.posted_at
+`, p.ItemID, p.Destination, p.RemoteID, strings.Join(p.Replies, ","), p.Status, p.Error, p.PostedAt.UTC())
+	return err
+}
+
+// Posts returns the delivery record of item id, keyed by destination.
+func (s *Store) Posts(ctx context.Context, id int64) (map[string]Post, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+SELECT item_id,destination,remote_id,reply_ids,status,error,posted_at
+FROM posts WHERE item_id=$1`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]Post{}
+	for rows.Next() {
+		var p Post
+		var replies string
+		if err := rows.Scan(&p.ItemID, &p.Destination, &p.RemoteID, &replies, &p.Status, &p.Error, &p.PostedAt); err != nil {
+			return nil, err
+		}
+		if replies != "" {
+			p.Replies = strings.Split(replies, ",")
+		}
+		out[p.Destination] = p
+	}
+	return out, rows.Err()
+}
+
+// LastSent returns when destination last had an item delivered, or the
+// zero time if it never had one.
+func (s *Store) LastSent(ctx context.Context, destination string) (time.Time, error) {
+	var t time.Time
+	err := s.DB.QueryRowContext(ctx, `
+SELECT posted_at FROM posts WHERE destination=$1 AND status='sent'
+ORDER BY posted_at DESC LIMIT 1`, destination).Scan(&t)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	return t, err
+}
+
+// NextUnposted returns up to limit items published since since and scoring
+// at least minScore that none of destinations has had yet, sent, failed or
+// given up on. An item already delivered to one of them is not sent to a
+// destination added later. Deliveries elsewhere, such as email digests,
+// do not count. Items come best scored first; a limit of 0 returns every
+// such item.
+func (s *Store) NextUnposted(ctx context.Context, destinations []string, minScore float64, since time.Time, limit int) ([]Item, error) {
+	if len(destinations) == 0 {
+		return nil, nil
+	}
+	args, marks := destinationArgs([]any{minScore, since.UTC()}, destinations)
+	limitClause := ""
+	if limit > 0 {
+		args = append(args, limit)
+		limitClause = fmt.Sprintf("\nLIMIT $%d", len(args))
+	}
+	rows, err := s.DB.QueryContext(ctx, `
+SELECT id,source,title,url,summary,published_at,tags,hash,score,score_detail,image,`+postsIn("'sent'", marks)+`
+FROM items
+WHERE canonical_id IS NULL AND score >= $1 AND published_at >= $2
+  AND NOT `+postsIn("'sent','failed','dead'", marks)+`
+ORDER BY score DESC, published_at DESC`+limitClause, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanItems(rows)
+}
+
+// NextRetries returns up to limit items published since since and scoring
+// at least minScore whose last attempt at one of destinations failed,
+// least recently tried first. They are selected apart from NextUnposted so
+// a destination that keeps failing cannot hold back new items, and in
+// rotation so it cannot pin the same few either.
+func (s *Store) NextRetries(ctx context.Context, destinations []string, minScore float64, since time.Time, limit int) ([]Item, error) {
+	if len(destinations) == 0 {
+		return nil, nil
+	}
+	args, marks := destinationArgs([]any{minScore, since.UTC(), limit}, destinations)
+	rows, err := s.DB.QueryContext(ctx, `
+SELECT id,source,title,url,summary,published_at,tags,hash,score,score_detail,image,`+postsIn("'sent'", marks)+`
+FROM items
+WHERE canonical_id IS NULL AND score >= $1 AND published_at >= $2
+  AND `+postsIn("'failed'", marks)+`
+ORDER BY (SELECT MIN(posted_at) FROM posts WHERE posts.item_id=items.id AND posts.status='failed'
+    AND posts.destination IN (`+marks+`)), id
+LIMIT $3`, args...)
+	if err != nil {
+		return nil, err
+	}
+	return scanItems(rows)
+}
+
+// destinationArgs appends destinations to args and returns them with the
+// placeholder list that binds them, e.g. "$3,$4".
+func destinationArgs(args []any, destinations []string) ([]any, string) {
+	marks := make([]string, len(destinations))
+	for i, d := range destinations {
+		args = append(args, d)
+		marks[i] = fmt.Sprintf("$%d", len(args))
+	}
+	return args, strings.Join(marks, ",")
+}
+
+// NextUndelivered returns up to limit items published since since that
+// score at least minScore and have not been sent to destination yet, best
+// first. Unlike NextUnposted it ignores the other destinations, so a
+// digest sees items the channels have already sent.
+func (s *Store) NextUndelivered(ctx context.Context, destination string, minScore float64, since time.Time, limit int) ([]Item, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+SELECT id,source,title,url,summary,published_at,tags,hash,score,score_detail,image,`+postedExpr+`
+FROM items
+WHERE canonical_id IS NULL AND score >= $1 AND published_at >= $2
+  AND id NOT IN (SELECT item_id FROM posts WHERE destination=$3 AND status='sent')
+ORDER BY score DESC, published_at DESC
+LIMIT $4`, minScore, since.UTC(), destination, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanItems(rows)
+}
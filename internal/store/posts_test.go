@@ -0,0 +1,134 @@
+package store
+
+import (
+	"context"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	db, err := Open("file:" + filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// addItems stores one item per title, best scored first, and returns
+// their ids by title.
+func addItems(t *testing.T, db *Store, titles ...string) map[string]int64 {
+	t.Helper()
+	ctx := context.Background()
+	ids := map[string]int64{}
+	for i, title := range titles {
+		it := Item{
+			Source: "test", Title: title, URL: "https://example.com/" + title,
+			PublishedAt: time.Now().UTC().Add(-time.Hour), Hash: Hash(title, title),
+			Score: 0.9 - float64(i)/10,
+		}
+		if _, err := db.InsertIfNew(ctx, it); err != nil {
+			t.Fatal(err)
+		}
+		var id int64
+		if err := db.DB.QueryRowContext(ctx, `SELECT id FROM items WHERE hash=$1`, it.Hash).Scan(&id); err != nil {
+			t.Fatal(err)
+		}
+		ids[title] = id
+	}
+	return ids
+}
+
+func titles(items []Item) string {
+	var out []string
+	for _, it := range items {
+		out = append(out, it.Title)
+	}
+	sort.Strings(out)
+	return strings.Join(out, " ")
+}
+
+var since = time.Now().Add(-24 * time.Hour)
+
+// TestNextUnpostedIgnoresOtherDestinations checks that an item in an email
+// digest still reaches the channels.
+func TestNextUnpostedIgnoresOtherDestinations(t *testing.T) {
+	ctx := context.Background()
+	db := openTestStore(t)
+	ids := addItems(t, db, "a")
+	if err := db.RecordPost(ctx, Post{ItemID: ids["a"], Destination: "digest", Status: PostSent}); err != nil {
+		t.Fatal(err)
+	}
+
+	items, err := db.NextUnposted(ctx, []string{"telegram"}, 0, since, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 1 || items[0].Posted {
+		t.Fatalf("NextUnposted(telegram) = %+v, want item a, not posted", items)
+	}
+
+	if err := db.RecordPost(ctx, Post{ItemID: ids["a"], Destination: "telegram", Status: PostSent}); err != nil {
+		t.Fatal(err)
+	}
+	items, err = db.NextUnposted(ctx, []string{"telegram", "slack"}, 0, since, 10)
+	if err != nil || len(items) != 0 {
+		t.Fatalf("NextUnposted after telegram sent = %v, %v; want nothing", titles(items), err)
+	}
+}
+
+// TestFailingDestinationDoesNotStarveNewItems runs three batches of two
+// with slack failing every time: each run must still pick up new items,
+// and the failed ones come back as retries in rotation.
+func TestFailingDestinationDoesNotStarveNewItems(t *testing.T) {
+	ctx := context.Background()
+	db := openTestStore(t)
+	ids := addItems(t, db, "a", "b", "c", "d", "e")
+	dests := []string{"telegram", "slack"}
+
+	var newPerRun, retriesPerRun []string
+	for run := 0; run < 3; run++ {
+		fresh, err := db.NextUnposted(ctx, dests, 0, since, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		retries, err := db.NextRetries(ctx, dests, 0, since, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		newPerRun = append(newPerRun, titles(fresh))
+		retriesPerRun = append(retriesPerRun, titles(retries))
+		for _, it := range append(fresh, retries...) {
+			if err := db.RecordPost(ctx, Post{ItemID: it.ID, Destination: "telegram", Status: PostSent}); err != nil {
+				t.Fatal(err)
+			}
+			if err := db.RecordPost(ctx, Post{ItemID: it.ID, Destination: "slack", Status: PostFailed, Error: "503"}); err != nil {
+				t.Fatal(err)
+			}
+			time.Sleep(time.Millisecond) // distinct posted_at
+		}
+	}
+
+	if got, want := strings.Join(newPerRun, "|"), "a b|c d|e"; got != want {
+		t.Errorf("new items per run = %q, want %q", got, want)
+	}
+	if got, want := strings.Join(retriesPerRun, "|"), "|a b|c d"; got != want {
+		t.Errorf("retries per run = %q, want %q", got, want)
+	}
+
+	// Once slack recovers, a retried item is settled.
+	if err := db.RecordPost(ctx, Post{ItemID: ids["e"], Destination: "slack", Status: PostSent}); err != nil {
+		t.Fatal(err)
+	}
+	retries, err := db.NextRetries(ctx, dests, 0, since, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := titles(retries); got != "a b c d" {
+		t.Errorf("retries after e went out = %q, want a b c d", got)
+	}
+}
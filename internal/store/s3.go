@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// S3API is the subset of *s3.Client used by S3Snapshot, so tests can
+// swap in a local fake.
+type S3API interface {
+	GetObject(ctx context.Context, in *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, in *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// ErrSnapshotConflict is returned by Upload when the object changed in S3
+// since Download, i.e. another invocation wrote it first.
+var ErrSnapshotConflict = errors.New("store: s3 snapshot was modified concurrently")
+
+// S3Snapshot keeps a local SQLite file in sync with an S3 object so state
+// survives Lambda cold starts. Download before Open, Upload after Close.
+type S3Snapshot struct {
+	Client S3API
+	Bucket string
+	Key    string
+	Path   string // local database file
+
+	etag string // ETag seen at Download; empty if the object did not exist
+}
+
+// Download fetches the object into Path. A missing object is not an error:
+// the store starts empty and Upload will create it.
+func (s *S3Snapshot) Download(ctx context.Context) error {
+	out, err := s.Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.Key),
+	})
+	if err != nil {
+		var nsk *types.NoSuchKey
+		if errors.As(err, &nsk) {
+			s.etag = ""
+			return nil
+		}
+		return fmt.Errorf("s3 get %s/%s: %w", s.Bucket, s.Key, err)
+	}
+	defer out.Body.Close()
+
+	if err := os.MkdirAll(filepath.Dir(s.Path), 0o755); err != nil {
+		return err
+	}
+	tmp := s.Path + ".download"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(f, out.Body); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return fmt.Errorf("s3 get %s/%s: %w", s.Bucket, s.Key, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, s.Path); err != nil {
+		return err
+	}
+	s.etag = aws.ToString(out.ETag)
+	return nil
+}
+
+// Upload writes Path back to S3, conditional on the object still having the
+// ETag seen at Download (or still not existing).
+func (s *S3Snapshot) Upload(ctx context.Context) error {
+	f, err := os.Open(s.Path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	in := &s3.PutObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.Key),
+		Body:   f,
+	}
+	if s.etag != "" {
+		in.IfMatch = aws.String(s.etag)
+	} else {
+		in.IfNoneMatch = aws.String("*")
+	}
+	out, err := s.Client.PutObject(ctx, in)
+	if err != nil {
+		var ae smithy.APIError
+		if errors.As(err, &ae) && (ae.ErrorCode() == "PreconditionFailed" || ae.ErrorCode() == "ConditionalRequestConflict") {
+			return ErrSnapshotConflict
+		}
+		return fmt.Errorf("s3 put %s/%s: %w", s.Bucket, s.Key, err)
+	}
+	s.etag = aws.ToString(out.ETag)
+	return nil
+}
+
+// SQLitePath returns the database file behind a sqlite DSN, or false for
+// other backends and in-memory databases.
+func SQLitePath(connStr string) (string, bool) {
+	d, dsn, err := parseDSN(connStr)
+	if err != nil || d != sqlite {
+		return "", false
+	}
+	p := strings.TrimPrefix(dsn, "file:")
+	if i := strings.IndexByte(p, '?'); i >= 0 {
+		p = p[:i]
+	}
+	if p == "" || p == ":memory:" {
+		return "", false
+	}
+	return p, true
+}
@@ -0,0 +1,158 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+)
+
+// fakeS3 is an in-memory S3API that honours IfMatch and IfNoneMatch like
+// S3 does.
+type fakeS3 struct {
+	objects map[string][]byte
+	etags   map[string]string
+	puts    []*s3.PutObjectInput
+	n       int
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: map[string][]byte{}, etags: map[string]string{}}
+}
+
+func (f *fakeS3) GetObject(ctx context.Context, in *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	key := aws.ToString(in.Bucket) + "/" + aws.ToString(in.Key)
+	body, ok := f.objects[key]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(body)), ETag: aws.String(f.etags[key])}, nil
+}
+
+func (f *fakeS3) PutObject(ctx context.Context, in *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	f.puts = append(f.puts, in)
+	key := aws.ToString(in.Bucket) + "/" + aws.ToString(in.Key)
+	etag, exists := f.etags[key]
+	if in.IfMatch != nil && (!exists || aws.ToString(in.IfMatch) != etag) ||
+		aws.ToString(in.IfNoneMatch) == "*" && exists {
+		return nil, &smithy.GenericAPIError{Code: "PreconditionFailed", Message: "At least one of the pre-conditions you specified did not hold"}
+	}
+	body, err := io.ReadAll(in.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.n++
+	f.objects[key], f.etags[key] = body, fmt.Sprintf(`"etag-%d"`, f.n)
+	return &s3.PutObjectOutput{ETag: aws.String(f.etags[key])}, nil
+}
+
+func TestS3SnapshotDownloadMissing(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.db")
+	snap := &S3Snapshot{Client: newFakeS3(), Bucket: "b", Key: "k", Path: path}
+	if err := snap.Download(context.Background()); err != nil {
+		t.Fatalf("Download() = %v, want nil for a missing object", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Download created %s for a missing object", path)
+	}
+}
+
+func TestS3SnapshotRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeS3()
+	path := filepath.Join(t.TempDir(), "data.db")
+	snap := &S3Snapshot{Client: fake, Bucket: "b", Key: "k", Path: path}
+
+	if err := snap.Download(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := snap.Upload(ctx); err != nil {
+		t.Fatalf("first Upload() = %v", err)
+	}
+
+	other := &S3Snapshot{Client: fake, Bucket: "b", Key: "k", Path: filepath.Join(t.TempDir(), "sub", "data.db")}
+	if err := other.Download(ctx); err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(other.Path)
+	if err != nil || string(got) != "v1" {
+		t.Fatalf("downloaded %q, %v; want v1", got, err)
+	}
+	if err := os.WriteFile(other.Path, []byte("v2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := other.Upload(ctx); err != nil {
+		t.Fatalf("Upload() after Download = %v", err)
+	}
+	if got := string(fake.objects["b/k"]); got != "v2" {
+		t.Errorf("object = %q, want v2", got)
+	}
+}
+
+func TestS3SnapshotPreconditions(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeS3()
+	path := filepath.Join(t.TempDir(), "data.db")
+	if err := os.WriteFile(path, []byte("v1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	snap := &S3Snapshot{Client: fake, Bucket: "b", Key: "k", Path: path}
+
+	if err := snap.Download(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := snap.Upload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := snap.Upload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.puts) != 2 {
+		t.Fatalf("%d puts, want 2", len(fake.puts))
+	}
+	first, second := fake.puts[0], fake.puts[1]
+	if aws.ToString(first.IfNoneMatch) != "*" || first.IfMatch != nil {
+		t.Errorf("creating put: IfNoneMatch=%q IfMatch=%v, want * and nil", aws.ToString(first.IfNoneMatch), first.IfMatch)
+	}
+	if aws.ToString(second.IfMatch) != `"etag-1"` || second.IfNoneMatch != nil {
+		t.Errorf("updating put: IfMatch=%q IfNoneMatch=%v, want the first ETag and nil", aws.ToString(second.IfMatch), second.IfNoneMatch)
+	}
+}
+
+func TestS3SnapshotConflict(t *testing.T) {
+	ctx := context.Background()
+	fake := newFakeS3()
+	dir := t.TempDir()
+	a := &S3Snapshot{Client: fake, Bucket: "b", Key: "k", Path: filepath.Join(dir, "a.db")}
+	b := &S3Snapshot{Client: fake, Bucket: "b", Key: "k", Path: filepath.Join(dir, "b.db")}
+	for _, s := range []*S3Snapshot{a, b} {
+		if err := s.Download(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(s.Path, []byte(s.Path), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := a.Upload(ctx); err != nil {
+		t.Fatalf("a.Upload() = %v", err)
+	}
+	if err := b.Upload(ctx); !errors.Is(err, ErrSnapshotConflict) {
+		t.Fatalf("b.Upload() = %v, want ErrSnapshotConflict", err)
+	}
+	if got := string(fake.objects["b/k"]); got != a.Path {
+		t.Errorf("object = %q, want a's write kept", got)
+	}
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// SourceState is the fetch health of one configured source.
+type SourceState struct {
+	Name                string
+	URL                 string
+	LastSuccess         time.Time // zero if never succeeded
+	LastError           string
+	LastErrorAt         time.Time
+	ConsecutiveFailures int
+	LastItemCount       int
+	TotalItems          int64
+	NextAttempt         time.Time // zero unless the source is backed off
+}
+
+// Quarantined reports whether the source should be skipped at now.
+func (st SourceState) Quarantined(now time.Time) bool {
+	return !st.NextAttempt.IsZero() && now.Before(st.NextAttempt)
+}
+
+const sourceStateCols = `name,url,last_success,last_error,last_error_at,consecutive_failures,last_item_count,total_items,next_attempt`
+
+func scanSourceState(sc interface{ Scan(...interface{}) error }) (SourceState, error) {
+	var st SourceState
+	var lastSuccess, lastErrorAt, nextAttempt sql.NullTime
+	err := sc.Scan(&st.Name, &st.URL, &lastSuccess, &st.LastError, &lastErrorAt,
+		&st.ConsecutiveFailures, &st.LastItemCount, &st.TotalItems, &nextAttempt)
+	st.LastSuccess = lastSuccess.Time
+	st.LastErrorAt = lastErrorAt.Time
+	st.NextAttempt = nextAttempt.Time
+	return st, err
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
+
+// SourceStates returns the state of every source seen so far, keyed by name.
+func (s *Store) SourceStates(ctx context.Context) (map[string]SourceState, error) {
+	rows, err := s.DB.QueryContext(ctx, `SELECT `+sourceStateCols+` FROM source_state`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := map[string]SourceState{}
+	for rows.Next() {
+		st, err := scanSourceState(rows)
+		if err != nil {
+			return nil, err
+		}
+		out[st.Name] = st
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) SaveSourceState(ctx context.Context, st SourceState) error {
+	_, err := s.DB.ExecContext(ctx, `
+INSERT INTO source_state (`+sourceStateCols+`)
+VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+ON CONFLICT(name) DO UPDATE SET
+    url=excluded.url,
+    last_success=excluded.last_success,
+    last_error=excluded.last_error,
+    last_error_at=excluded.last_error_at,
+    consecutive_failures=excluded.consecutive_failures,
+    last_item_count=excluded.last_item_count,
+    total_items=excluded.total_items,
+    next_attempt=excluded.next_attempt
+`, st.Name, st.URL, nullTime(st.LastSuccess), st.LastError, nullTime(st.LastErrorAt),
+		st.ConsecutiveFailures, st.LastItemCount, st.TotalItems, nullTime(st.NextAttempt))
+	return err
+}
+
+// UnhealthySources lists sources whose latest fetch failed, worst first.
+func (s *Store) UnhealthySources(ctx context.Context) ([]SourceState, error) {
+	rows, err := s.DB.QueryContext(ctx, `
+SELECT `+sourceStateCols+`
+FROM source_state
+WHERE consecutive_failures > 0
+ORDER BY consecutive_failures DESC, name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []SourceState
+	for rows.Next() {
+		st, err := scanSourceState(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, st)
+	}
+	return out, rows.Err()
+}
@@ -18,12 +18,33 @@ func CanonURL(raw string) string {
 	return u.String()
 }
 
+// EscapeTelegram escapes text for Telegram's MarkdownV2 outside of links
+// and code: every reserved character, and the backslash itself, gets a
+// preceding backslash.
 func EscapeTelegram(s string) string {
-	// minimal escaping for MarkdownV2
 	replacer := strings.NewReplacer(
-		"_","\\_","*","\\*","[","\\[","]","\\]", "(", "\\(", ")", "\\)",
-		"~","\\~","`","\\`",">","\\>", "#","\\#", "+","\\+","-","\\-",
-		"=","\\=","|","\\|","{","\\{","}","\\}",".","\\.","!","\\!",
+		"\\", "\\\\",
+		"_", "\\_", "*", "\\*", "[", "\\[", "]", "\\]", "(", "\\(", ")", "\\)",
+		"~", "\\~", "`", "\\`", ">", "\\>", "#", "\\#", "+", "\\+", "-", "\\-",
+		"=", "\\=", "|", "\\|", "{", "\\{", "}", "\\}", ".", "\\.", "!", "\\!",
 	)
 	return replacer.Replace(s)
 }
+
+// EscapeTelegramURL escapes the target of a MarkdownV2 inline link,
+// (...), where only ")" and the backslash must be escaped.
+func EscapeTelegramURL(u string) string {
+	return strings.NewReplacer("\\", "\\\\", ")", "\\)").Replace(u)
+}
+
+// EscapeTelegramHTML escapes text, or a quoted attribute value such as an
+// href, for Telegram's HTML parse mode.
+func EscapeTelegramHTML(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;").Replace(s)
+}
+
+// EscapeSlack escapes the three characters Slack's mrkdwn treats as control
+// sequences. Markdown-ish characters (*, _, ~) have no escape in Slack.
+func EscapeSlack(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
@@ -0,0 +1,34 @@
+package util
+
+import "testing"
+
+func TestEscapeTelegram(t *testing.T) {
+	tests := []struct {
+		name, in, text, url, html string
+	}{
+		{"plain", "Kubernetes", "Kubernetes", "Kubernetes", "Kubernetes"},
+		{"reserved", "v1.31: (GA) #k8s", `v1\.31: \(GA\) \#k8s`, `v1.31: (GA\) #k8s`, "v1.31: (GA) #k8s"},
+		{"every reserved", "_*[]()~`>#+-=|{}.!", "\\_\\*\\[\\]\\(\\)\\~\\`\\>\\#\\+\\-\\=\\|\\{\\}\\.\\!", "_*[](\\)~`>#+-=|{}.!", "_*[]()~`&gt;#+-=|{}.!"},
+		{"backslash", `C:\dir`, `C:\\dir`, `C:\\dir`, `C:\dir`},
+		{"escape is not doubled", `a\.b`, `a\\\.b`, `a\\.b`, `a\.b`},
+		{"url", "https://en.wikipedia.org/wiki/Go_(language)?a=1&b=2",
+			`https://en\.wikipedia\.org/wiki/Go\_\(language\)?a\=1&b\=2`,
+			`https://en.wikipedia.org/wiki/Go_(language\)?a=1&b=2`,
+			`https://en.wikipedia.org/wiki/Go_(language)?a=1&amp;b=2`},
+		{"html", `<b>"Tom & Jerry"</b>`, `<b\>"Tom & Jerry"</b\>`, `<b>"Tom & Jerry"</b>`, `&lt;b&gt;&quot;Tom &amp; Jerry&quot;&lt;/b&gt;`},
+		{"unicode", "Grüße 🚀", "Grüße 🚀", "Grüße 🚀", "Grüße 🚀"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EscapeTelegram(tt.in); got != tt.text {
+				t.Errorf("EscapeTelegram(%q) = %q, want %q", tt.in, got, tt.text)
+			}
+			if got := EscapeTelegramURL(tt.in); got != tt.url {
+				t.Errorf("EscapeTelegramURL(%q) = %q, want %q", tt.in, got, tt.url)
+			}
+			if got := EscapeTelegramHTML(tt.in); got != tt.html {
+				t.Errorf("EscapeTelegramHTML(%q) = %q, want %q", tt.in, got, tt.html)
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package util
+
+import (
+	"hash/fnv"
+	"math/bits"
+	"strings"
+	"unicode"
+)
+
+// SimHash returns a 64-bit locality-sensitive fingerprint of an item's
+// title and summary: texts that differ in a few words differ in a few bits.
+// Words are lower-cased and stripped of punctuation, then hashed in pairs
+// so word order counts. The title weighs as much as the whole summary:
+// syndicated copies of a post keep its title but often quote a different
+// excerpt of it.
+func SimHash(title, summary string) uint64 {
+	tw, sw := simWords(title), simWords(summary)
+	if len(tw) == 0 && len(sw) == 0 {
+		return 0
+	}
+
+	var v [64]int
+	if len(tw) > 0 {
+		addShingles(&v, tw, 1+len(sw)/len(tw))
+	}
+	addShingles(&v, sw, 1)
+
+	var out uint64
+	for i := 0; i < 64; i++ {
+		if v[i] > 0 {
+			out |= 1 << uint(i)
+		}
+	}
+	return out
+}
+
+func simWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// addShingles adds every pair of adjacent words (the word itself when there
+// is only one) to the bit counts v with the given weight.
+func addShingles(v *[64]int, words []string, weight int) {
+	add := func(s string) {
+		h := fnv.New64a()
+		h.Write([]byte(s))
+		x := h.Sum64()
+		for i := 0; i < 64; i++ {
+			if x&(1<<uint(i)) != 0 {
+				v[i] += weight
+			} else {
+				v[i] -= weight
+			}
+		}
+	}
+	if len(words) == 1 {
+		add(words[0])
+	}
+	for i := 0; i+1 < len(words); i++ {
+		add(words[i] + " " + words[i+1])
+	}
+}
+
+// HammingDistance counts the differing bits of two SimHash values.
+func HammingDistance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
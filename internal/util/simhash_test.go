@@ -0,0 +1,112 @@
+package util
+
+import "testing"
+
+// maxDistance is the dedup.max_distance default the pairs below are
+// calibrated against.
+const maxDistance = 11
+
+type simItem struct{ title, summary string }
+
+// TestSimHashDuplicates measures syndicated copies of one post, which must
+// be linked, against different posts on the same topics, which must not.
+func TestSimHashDuplicates(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b simItem
+		dup  bool
+	}{
+		{
+			"identical",
+			simItem{"Kubernetes v1.31: Elli", "Kubernetes v1.31 is out with 45 enhancements."},
+			simItem{"Kubernetes v1.31: Elli", "Kubernetes v1.31 is out with 45 enhancements."},
+			true,
+		},
+		{
+			"case and punctuation",
+			simItem{"Kubernetes v1.31 released with 45 enhancements, 11 of them graduating to stable", ""},
+			simItem{"KUBERNETES v1.31 released -- with 45 enhancements; 11 of them graduating to stable!", ""},
+			true,
+		},
+		{
+			"one word changed",
+			simItem{"Kubernetes v1.31 released with 45 enhancements, 11 of them graduating to stable", ""},
+			simItem{"Kubernetes v1.31 released with 45 enhancements, 11 of them graduating to GA", ""},
+			true,
+		},
+		{
+			"shorter excerpt",
+			simItem{"Kubernetes v1.31: Elli", "Kubernetes v1.31 is out with 45 enhancements. 11 have graduated to stable, 22 are entering beta and 12 are alpha."},
+			simItem{"Kubernetes v1.31: Elli", "Kubernetes v1.31 is out with 45 enhancements. Originally published on kubernetes.io."},
+			true,
+		},
+		{
+			"reworded",
+			simItem{"Announcing Istio 1.23.0", "We are pleased to announce the release of Istio 1.23. This release brings ambient mode improvements."},
+			simItem{"Announcing Istio 1.23", "We are pleased to announce the release of Istio 1.23! Ambient mode improvements and more."},
+			true,
+		},
+		{
+			"title case",
+			simItem{"Prometheus 3.0 beta released", "The Prometheus team is proud to announce the beta of Prometheus 3.0, with a new UI and remote write 2.0."},
+			simItem{"Prometheus 3.0 Beta Released", "Today the Prometheus team announces the 3.0 beta with a brand new UI, remote write 2.0 and UTF-8 metric names."},
+			true,
+		},
+		{
+			"other release",
+			simItem{"Kubernetes v1.31: Elli", "Kubernetes v1.31 is out with 45 enhancements. 11 have graduated to stable, 22 are entering beta and 12 are alpha."},
+			simItem{"Kubernetes v1.32: Penelope", "Kubernetes v1.32 is out with 44 enhancements. 13 have graduated to stable, 12 are entering beta and 19 are alpha."},
+			false,
+		},
+		{
+			"same project",
+			simItem{"Kubernetes 1.31: Read Only Volumes Based On OCI Artifacts", "Kubernetes v1.31 adds a new alpha feature that lets a pod mount an OCI image or artifact as a volume."},
+			simItem{"Kubernetes 1.31: Pod Failure Policy for Jobs Goes GA", "The pod failure policy for Jobs graduates to stable in Kubernetes v1.31."},
+			false,
+		},
+		{
+			"same event",
+			simItem{"KubeCon Europe 2025 schedule announced", "The schedule for KubeCon + CloudNativeCon Europe 2025 in London is live."},
+			simItem{"KubeCon North America 2024 recap", "Highlights from KubeCon + CloudNativeCon North America 2024 in Salt Lake City."},
+			false,
+		},
+		{
+			"unrelated",
+			simItem{"Kubernetes v1.31 released with 45 enhancements, 11 of them graduating to stable", ""},
+			simItem{"Prometheus adds native histograms and a new remote write protocol for agents", ""},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := HammingDistance(SimHash(tt.a.title, tt.a.summary), SimHash(tt.b.title, tt.b.summary))
+			if dup := d <= maxDistance; dup != tt.dup {
+				t.Errorf("distance = %d, duplicate = %v; want %v at max_distance %d", d, dup, tt.dup, maxDistance)
+			}
+		})
+	}
+}
+
+func TestSimHashEmpty(t *testing.T) {
+	if got := SimHash(" -- !! ", ""); got != 0 {
+		t.Errorf("SimHash of punctuation = %x, want 0", got)
+	}
+	if SimHash("", "Kubernetes") == 0 || SimHash("Kubernetes", "") == 0 {
+		t.Error("SimHash of a single word = 0, want a fingerprint")
+	}
+}
+
+func TestHammingDistance(t *testing.T) {
+	for _, tt := range []struct {
+		a, b uint64
+		want int
+	}{
+		{0, 0, 0},
+		{0, ^uint64(0), 64},
+		{0b1011, 0b0110, 3},
+	} {
+		if got := HammingDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("HammingDistance(%b, %b) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}